@@ -0,0 +1,145 @@
+package log
+
+import (
+	"path"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// CallerPath decides how caller's filename is saved in Message.Filename
+type CallerPath uint32
+
+const (
+	// CallerBase saves base name of caller's file, e.g. "log.go"
+	CallerBase CallerPath = iota
+	// CallerRelative saves path of caller's file relative to its module root, e.g.
+	// "cmd/app/main.go"
+	CallerRelative
+	// CallerFull saves full path of caller's file
+	CallerFull
+)
+
+const defaultCallerLevels = 1 << LevelTrace
+
+var (
+	modulesOnce sync.Once
+	modules     []string // paths of modules the binary built with
+	mainPackage string   // import path of the main package
+)
+
+// SetCallerLevels sets levels whose messages are logged with caller's filename, line
+// and function, default only trace messages are logged with caller
+func (e *Engine) SetCallerLevels(levels ...Level) {
+	var mask uint32
+	for _, level := range levels {
+		mask |= 1 << level
+	}
+
+	atomic.StoreUint32(&e.callerLevels, mask)
+}
+
+// SetCallerPath sets how caller's filename is saved, default CallerBase
+func (e *Engine) SetCallerPath(mode CallerPath) {
+	atomic.StoreUint32(&e.callerPath, uint32(mode))
+}
+
+// AddCallerSkip returns an entry of engine skips n more stack frames when finding
+// caller, it's used by functions wrap log functions to report their callers.
+func (e *Engine) AddCallerSkip(n int) *Entry {
+	return &Entry{engine: e, skip: n}
+}
+
+// AddCallerSkip returns a new entry skips n more stack frames when finding caller
+func (e *Entry) AddCallerSkip(n int) *Entry {
+	return &Entry{
+		engine: e.engine,
+		fields: e.fields,
+		keys:   e.keys,
+		skip:   e.skip + n,
+	}
+}
+
+// SetCallerLevels sets levels whose messages are logged with caller of log engine
+func SetCallerLevels(levels ...Level) {
+	logctx.SetCallerLevels(levels...)
+}
+
+// SetCallerPath sets how caller's filename is saved of log engine
+func SetCallerPath(mode CallerPath) {
+	logctx.SetCallerPath(mode)
+}
+
+// AddCallerSkip returns an entry of log engine skips n more stack frames when
+// finding caller
+func AddCallerSkip(n int) *Entry {
+	return logctx.AddCallerSkip(n)
+}
+
+// withCaller reports whether messages of level are logged with caller
+func (e *Engine) withCaller(level Level) bool {
+	return atomic.LoadUint32(&e.callerLevels)&(1<<level) != 0
+}
+
+// setCaller sets caller of message from program counter
+func (e *Engine) setCaller(msg *Message, pc uintptr) {
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	if frame.PC == 0 {
+		return
+	}
+
+	msg.Line = frame.Line
+	msg.Function = frame.Function
+
+	switch CallerPath(atomic.LoadUint32(&e.callerPath)) {
+	case CallerFull:
+		msg.Filename = frame.File
+	case CallerRelative:
+		msg.Filename = relativePath(frame.File, frame.Function)
+	default:
+		msg.Filename = filepath.Base(frame.File)
+	}
+}
+
+// relativePath returns path of file relative to root of the module, which contains
+// package of function. Path starts with package's import path if module is unknown.
+func relativePath(file, function string) string {
+	modulesOnce.Do(func() {
+		if info, ok := debug.ReadBuildInfo(); ok {
+			mainPackage = info.Path
+			modules = append(modules, info.Main.Path)
+			for _, dep := range info.Deps {
+				modules = append(modules, dep.Path)
+			}
+		}
+	})
+
+	// function is qualified by package's import path, e.g. "github.com/a/b.(*T).M",
+	// dots in the last element of import path are escaped, e.g. "gopkg.in/yaml%2ev3"
+	pkg := function
+	slash := strings.LastIndexByte(pkg, '/')
+	if dot := strings.IndexByte(pkg[slash+1:], '.'); dot >= 0 {
+		pkg = pkg[:slash+1+dot]
+	}
+	pkg = strings.ReplaceAll(pkg, "%2e", ".")
+
+	if pkg == "main" && mainPackage != "" {
+		pkg = mainPackage
+	}
+
+	module := ""
+	for _, mod := range modules {
+		if len(mod) > len(module) && (pkg == mod || strings.HasPrefix(pkg, mod+"/")) {
+			module = mod
+		}
+	}
+
+	if module != "" {
+		pkg = strings.TrimPrefix(strings.TrimPrefix(pkg, module), "/")
+	}
+
+	return path.Join(pkg, filepath.Base(file))
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"runtime"
 	"strings"
+	"sync/atomic"
 
 	"github.com/mattn/go-isatty"
 )
@@ -12,9 +13,11 @@ import (
 //Console logger, print log message to console, and each message level has
 //different color
 type console struct {
-	level     Level
+	name      string
+	level     uint32 // Level, accessed atomically
 	colors    []string
-	formatter Formatter
+	formatter formatterValue
+	redactors []Redactor
 }
 
 func Colorful() Option {
@@ -39,10 +42,12 @@ func Colorful() Option {
 //NewConsoleLogger creates a new console logger
 func NewConsoleLogger(level Level, options ...Option) Logger {
 	cl := &console{
-		level:     level,
-		formatter: new(TextFormatter),
+		name:  Console,
+		level: uint32(level),
 	}
 
+	cl.formatter.Store(new(TextFormatter))
+
 	for _, option := range options {
 		option(cl)
 	}
@@ -51,11 +56,15 @@ func NewConsoleLogger(level Level, options ...Option) Logger {
 }
 
 func (c *console) Name() string {
-	return Console
+	return c.name
 }
 
 func (c *console) Level() Level {
-	return c.level
+	return Level(atomic.LoadUint32(&c.level))
+}
+
+func (c *console) SetLevel(level Level) {
+	atomic.StoreUint32(&c.level, uint32(level))
 }
 
 func (c *console) Write(msg *Message) {
@@ -72,9 +81,11 @@ func (c *console) Close() error {
 }
 
 func (c *console) Format(msg *Message) string {
-	if c.formatter == nil {
-		c.formatter = new(TextFormatter)
+	msg = redact(msg, c.redactors)
+	formatter := c.formatter.Load()
+	if formatter == nil {
+		formatter = defaultFormatter
 	}
 
-	return c.formatter.Format(msg)
+	return formatter.Format(msg)
 }
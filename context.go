@@ -0,0 +1,190 @@
+package log
+
+import (
+	"context"
+)
+
+type entryKey struct{}
+
+// ContextExtractor extracts fields from context, e.g. trace and span IDs
+type ContextExtractor func(ctx context.Context) Fields
+
+// NewContext returns a copy of ctx carrying entry
+func NewContext(ctx context.Context, entry *Entry) context.Context {
+	return context.WithValue(ctx, entryKey{}, entry)
+}
+
+// ContextWithFields returns a copy of ctx carrying the entry from ctx with fields
+// added. If ctx carries no entry, the entry carries fields only, and messages are
+// logged by the engine whose XxxContext function is called.
+func ContextWithFields(ctx context.Context, fields Fields) context.Context {
+	entry := contextEntry(ctx)
+	if entry == nil {
+		entry = &Entry{}
+	}
+
+	return NewContext(ctx, entry.WithFields(fields))
+}
+
+// FromContext returns entry carried by ctx, an entry of log engine without fields
+// is returned if ctx carries no entry
+func FromContext(ctx context.Context) *Entry {
+	entry := contextEntry(ctx)
+	if entry == nil {
+		return &Entry{engine: logctx}
+	}
+
+	if entry.engine == nil {
+		return &Entry{engine: logctx, fields: entry.fields, keys: entry.keys, skip: entry.skip}
+	}
+
+	return entry
+}
+
+// contextEntry returns entry carried by ctx, or nil if ctx carries no entry
+func contextEntry(ctx context.Context) *Entry {
+	if ctx != nil {
+		if entry, ok := ctx.Value(entryKey{}).(*Entry); ok {
+			return entry
+		}
+	}
+
+	return nil
+}
+
+// AddContextExtractor adds an extractor to engine, fields extracted from context
+// are logged with messages of the XxxContext functions
+func (e *Engine) AddContextExtractor(extractor ContextExtractor) {
+	if extractor == nil {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	current, _ := e.extractors.Load().([]ContextExtractor)
+	extractors := make([]ContextExtractor, len(current), len(current)+1)
+	copy(extractors, current)
+	e.extractors.Store(append(extractors, extractor))
+}
+
+// AddContextExtractor adds an extractor to log engine
+func AddContextExtractor(extractor ContextExtractor) {
+	logctx.AddContextExtractor(extractor)
+}
+
+// contextFields returns fields of entry merged with fields extracted from ctx by
+// engine's extractors, and their keys in order
+func (e *Engine) contextFields(ctx context.Context, entry *Entry) (fields Fields, keys []string) {
+	if entry != nil {
+		fields, keys = entry.fields, entry.keys
+	}
+
+	if ctx == nil {
+		return fields, keys
+	}
+
+	extractors, _ := e.extractors.Load().([]ContextExtractor)
+	for _, extract := range extractors {
+		fields, keys = mergeFields(fields, keys, extract(ctx), nil)
+	}
+
+	return fields, keys
+}
+
+// logContext logs message with fields carried by ctx, which are only extracted if
+// message will be dispatched. Message is logged by engine of the entry carried by
+// ctx with its caller skip, or by e if ctx carries no entry.
+func (e *Engine) logContext(ctx context.Context, level Level, message string) {
+	engine, skip := e, 0
+	entry := contextEntry(ctx)
+	if entry != nil && entry.engine != nil {
+		engine, skip = entry.engine, entry.skip
+	}
+
+	if engine.enabled(level) {
+		fields, keys := engine.contextFields(ctx, entry)
+		engine.output(skip, level, message, fields, keys)
+	}
+	engine.terminate(level, message)
+}
+
+// TraceContext print trace message with fields carried by ctx
+func (e *Engine) TraceContext(ctx context.Context, args ...interface{}) {
+	e.logContext(ctx, LevelTrace, formatLogMessage(args...))
+}
+
+// DebugContext print debug message with fields carried by ctx
+func (e *Engine) DebugContext(ctx context.Context, args ...interface{}) {
+	e.logContext(ctx, LevelDebug, formatLogMessage(args...))
+}
+
+// VerboseContext print verbose message with fields carried by ctx
+func (e *Engine) VerboseContext(ctx context.Context, args ...interface{}) {
+	e.logContext(ctx, LevelVerbose, formatLogMessage(args...))
+}
+
+// InfoContext print information message with fields carried by ctx
+func (e *Engine) InfoContext(ctx context.Context, args ...interface{}) {
+	e.logContext(ctx, LevelInfo, formatLogMessage(args...))
+}
+
+// WarningContext print warning message with fields carried by ctx
+func (e *Engine) WarningContext(ctx context.Context, args ...interface{}) {
+	e.logContext(ctx, LevelWarn, formatLogMessage(args...))
+}
+
+// ErrorContext print error message with fields carried by ctx
+func (e *Engine) ErrorContext(ctx context.Context, args ...interface{}) {
+	e.logContext(ctx, LevelError, formatLogMessage(args...))
+}
+
+// FatalContext print fatal error message with fields carried by ctx, and app will quit
+func (e *Engine) FatalContext(ctx context.Context, args ...interface{}) {
+	e.logContext(ctx, LevelFatal, formatLogMessage(args...))
+}
+
+// PanicContext print panic message with fields carried by ctx, and app will panic
+func (e *Engine) PanicContext(ctx context.Context, args ...interface{}) {
+	e.logContext(ctx, LevelPanic, formatLogMessage(args...))
+}
+
+// TraceContext print trace message with fields carried by ctx
+func TraceContext(ctx context.Context, args ...interface{}) {
+	logctx.logContext(ctx, LevelTrace, formatLogMessage(args...))
+}
+
+// DebugContext print debug message with fields carried by ctx
+func DebugContext(ctx context.Context, args ...interface{}) {
+	logctx.logContext(ctx, LevelDebug, formatLogMessage(args...))
+}
+
+// VerboseContext print verbose message with fields carried by ctx
+func VerboseContext(ctx context.Context, args ...interface{}) {
+	logctx.logContext(ctx, LevelVerbose, formatLogMessage(args...))
+}
+
+// InfoContext print information message with fields carried by ctx
+func InfoContext(ctx context.Context, args ...interface{}) {
+	logctx.logContext(ctx, LevelInfo, formatLogMessage(args...))
+}
+
+// WarningContext print warning message with fields carried by ctx
+func WarningContext(ctx context.Context, args ...interface{}) {
+	logctx.logContext(ctx, LevelWarn, formatLogMessage(args...))
+}
+
+// ErrorContext print error message with fields carried by ctx
+func ErrorContext(ctx context.Context, args ...interface{}) {
+	logctx.logContext(ctx, LevelError, formatLogMessage(args...))
+}
+
+// FatalContext print fatal error message with fields carried by ctx, and app will quit
+func FatalContext(ctx context.Context, args ...interface{}) {
+	logctx.logContext(ctx, LevelFatal, formatLogMessage(args...))
+}
+
+// PanicContext print panic message with fields carried by ctx, and app will panic
+func PanicContext(ctx context.Context, args ...interface{}) {
+	logctx.logContext(ctx, LevelPanic, formatLogMessage(args...))
+}
@@ -0,0 +1,210 @@
+package log
+
+import (
+	"fmt"
+)
+
+// ErrorKey is the field key of error attached by WithError
+const ErrorKey = "error"
+
+// Entry is an immutable logger carrying fields, which are logged with every message
+// of the entry. Deriving an entry by With, WithFields and WithError copies fields of
+// its parent, so an entry is safe to share between goroutines, and its fields are
+// never modified by children.
+type Entry struct {
+	engine *Engine
+	fields Fields
+	keys   []string // keys of fields in the order they were added
+	skip   int      // extra stack frames to skip when finding caller
+}
+
+// With returns a new entry with key and value added to fields
+func (e *Entry) With(key string, value interface{}) *Entry {
+	return e.WithFields(Fields{key: value})
+}
+
+// WithFields returns a new entry with fields added, fields with the same key are
+// overwritten, keys of fields are sorted when they're added
+func (e *Entry) WithFields(fields Fields) *Entry {
+	merged, keys := e.merge(fields, nil)
+	return &Entry{
+		engine: e.engine,
+		fields: merged,
+		keys:   keys,
+		skip:   e.skip,
+	}
+}
+
+// WithError returns a new entry with err added to fields with key ErrorKey
+func (e *Entry) WithError(err error) *Entry {
+	return e.WithFields(Fields{ErrorKey: err})
+}
+
+// internal log function
+func (e *Entry) log(level Level, message string, fields Fields, keys []string) {
+	e.engine.output(e.skip, level, message, fields, keys)
+	e.engine.terminate(level, message)
+}
+
+// merge returns a copy of entry's fields with fields added in the order of keys
+func (e *Entry) merge(fields Fields, keys []string) (Fields, []string) {
+	return mergeFields(e.fields, e.keys, fields, keys)
+}
+
+// With returns an entry of engine with key and value as fields
+func (e *Engine) With(key string, value interface{}) *Entry {
+	return e.WithFields(Fields{key: value})
+}
+
+// WithFields returns an entry of engine with a copy of fields
+func (e *Engine) WithFields(fields Fields) *Entry {
+	return (&Entry{engine: e}).WithFields(fields)
+}
+
+// WithError returns an entry of engine with err as field
+func (e *Engine) WithError(err error) *Entry {
+	return e.WithFields(Fields{ErrorKey: err})
+}
+
+// With returns an entry of log engine with key and value as fields
+func With(key string, value interface{}) *Entry {
+	return logctx.With(key, value)
+}
+
+// WithFields returns an entry of log engine with a copy of fields
+func WithFields(fields Fields) *Entry {
+	return logctx.WithFields(fields)
+}
+
+// WithError returns an entry of log engine with err as field
+func WithError(err error) *Entry {
+	return logctx.WithError(err)
+}
+
+// Trace print trace message, which prints more details
+func (e *Entry) Trace(args ...interface{}) {
+	e.log(LevelTrace, formatLogMessage(args...), e.fields, e.keys)
+}
+
+// Debug print debug message
+func (e *Entry) Debug(args ...interface{}) {
+	e.log(LevelDebug, formatLogMessage(args...), e.fields, e.keys)
+}
+
+// Verbose print verbose message
+func (e *Entry) Verbose(args ...interface{}) {
+	e.log(LevelVerbose, formatLogMessage(args...), e.fields, e.keys)
+}
+
+// Info print information message
+func (e *Entry) Info(args ...interface{}) {
+	e.log(LevelInfo, formatLogMessage(args...), e.fields, e.keys)
+}
+
+// Warning print warning message
+func (e *Entry) Warning(args ...interface{}) {
+	e.log(LevelWarn, formatLogMessage(args...), e.fields, e.keys)
+}
+
+// Error print error message
+func (e *Entry) Error(args ...interface{}) {
+	e.log(LevelError, formatLogMessage(args...), e.fields, e.keys)
+}
+
+// Fatal print fatal error message, and app will quit if this function called
+func (e *Entry) Fatal(args ...interface{}) {
+	e.log(LevelFatal, formatLogMessage(args...), e.fields, e.keys)
+}
+
+// Panic print panic message, and app will trigger panic message if called
+func (e *Entry) Panic(args ...interface{}) {
+	e.log(LevelPanic, formatLogMessage(args...), e.fields, e.keys)
+}
+
+// Tracef print trace message formatted according to format
+func (e *Entry) Tracef(format string, args ...interface{}) {
+	e.log(LevelTrace, fmt.Sprintf(format, args...), e.fields, e.keys)
+}
+
+// Debugf print debug message formatted according to format
+func (e *Entry) Debugf(format string, args ...interface{}) {
+	e.log(LevelDebug, fmt.Sprintf(format, args...), e.fields, e.keys)
+}
+
+// Verbosef print verbose message formatted according to format
+func (e *Entry) Verbosef(format string, args ...interface{}) {
+	e.log(LevelVerbose, fmt.Sprintf(format, args...), e.fields, e.keys)
+}
+
+// Infof print information message formatted according to format
+func (e *Entry) Infof(format string, args ...interface{}) {
+	e.log(LevelInfo, fmt.Sprintf(format, args...), e.fields, e.keys)
+}
+
+// Warningf print warning message formatted according to format
+func (e *Entry) Warningf(format string, args ...interface{}) {
+	e.log(LevelWarn, fmt.Sprintf(format, args...), e.fields, e.keys)
+}
+
+// Errorf print error message formatted according to format
+func (e *Entry) Errorf(format string, args ...interface{}) {
+	e.log(LevelError, fmt.Sprintf(format, args...), e.fields, e.keys)
+}
+
+// Fatalf print fatal error message formatted according to format, and app will quit
+func (e *Entry) Fatalf(format string, args ...interface{}) {
+	e.log(LevelFatal, fmt.Sprintf(format, args...), e.fields, e.keys)
+}
+
+// Panicf print panic message formatted according to format, and app will panic
+func (e *Entry) Panicf(format string, args ...interface{}) {
+	e.log(LevelPanic, fmt.Sprintf(format, args...), e.fields, e.keys)
+}
+
+// Tracew print trace message with alternating keys and values as fields
+func (e *Entry) Tracew(msg string, keysAndValues ...interface{}) {
+	fields, keys := e.merge(pairs(keysAndValues))
+	e.log(LevelTrace, msg, fields, keys)
+}
+
+// Debugw print debug message with alternating keys and values as fields
+func (e *Entry) Debugw(msg string, keysAndValues ...interface{}) {
+	fields, keys := e.merge(pairs(keysAndValues))
+	e.log(LevelDebug, msg, fields, keys)
+}
+
+// Verbosew print verbose message with alternating keys and values as fields
+func (e *Entry) Verbosew(msg string, keysAndValues ...interface{}) {
+	fields, keys := e.merge(pairs(keysAndValues))
+	e.log(LevelVerbose, msg, fields, keys)
+}
+
+// Infow print information message with alternating keys and values as fields
+func (e *Entry) Infow(msg string, keysAndValues ...interface{}) {
+	fields, keys := e.merge(pairs(keysAndValues))
+	e.log(LevelInfo, msg, fields, keys)
+}
+
+// Warningw print warning message with alternating keys and values as fields
+func (e *Entry) Warningw(msg string, keysAndValues ...interface{}) {
+	fields, keys := e.merge(pairs(keysAndValues))
+	e.log(LevelWarn, msg, fields, keys)
+}
+
+// Errorw print error message with alternating keys and values as fields
+func (e *Entry) Errorw(msg string, keysAndValues ...interface{}) {
+	fields, keys := e.merge(pairs(keysAndValues))
+	e.log(LevelError, msg, fields, keys)
+}
+
+// Fatalw print fatal error message with alternating keys and values as fields, and app will quit
+func (e *Entry) Fatalw(msg string, keysAndValues ...interface{}) {
+	fields, keys := e.merge(pairs(keysAndValues))
+	e.log(LevelFatal, msg, fields, keys)
+}
+
+// Panicw print panic message with alternating keys and values as fields, and app will panic
+func (e *Entry) Panicw(msg string, keysAndValues ...interface{}) {
+	fields, keys := e.merge(pairs(keysAndValues))
+	e.log(LevelPanic, msg, fields, keys)
+}
@@ -0,0 +1,32 @@
+package log
+
+import "strings"
+
+// multiError aggregates errors of loggers
+type multiError []error
+
+func (me multiError) Error() string {
+	msgs := make([]string, 0, len(me))
+	for _, err := range me {
+		msgs = append(msgs, err.Error())
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns aggregated errors, for errors.Is and errors.As
+func (me multiError) Unwrap() []error {
+	return me
+}
+
+// err returns nil if there is no error, and the error itself if there is only one
+func (me multiError) err() error {
+	switch len(me) {
+	case 0:
+		return nil
+	case 1:
+		return me[0]
+	default:
+		return me
+	}
+}
@@ -0,0 +1,71 @@
+package log
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// maximum time waiting for loggers to flush before app quits or panics
+const flushTimeout = 3 * time.Second
+
+var (
+	exitMu       sync.Mutex
+	exitHandlers []func()
+)
+
+// RegisterExitHandler adds a handler which runs before app quits by Fatal functions,
+// handlers run in the order they are registered.
+func RegisterExitHandler(handler func()) {
+	if handler == nil {
+		return
+	}
+
+	exitMu.Lock()
+	exitHandlers = append(exitHandlers, handler)
+	exitMu.Unlock()
+}
+
+func runExitHandlers() {
+	exitMu.Lock()
+	handlers := exitHandlers
+	exitMu.Unlock()
+
+	for _, handler := range handlers {
+		func() {
+			// a panicking handler must not prevent app from quitting
+			defer func() { recover() }()
+			handler()
+		}()
+	}
+}
+
+// terminate quits app for fatal messages, and panics for panic messages
+func (e *Engine) terminate(level Level, message string) {
+	switch level {
+	case LevelFatal:
+		runExitHandlers()
+		e.flush(flushTimeout)
+		os.Exit(1)
+	case LevelPanic:
+		e.flush(flushTimeout)
+		panic(message)
+	}
+}
+
+// flush waits until loggers have written pending messages or timeout
+func (e *Engine) flush(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		e.Sync()
+		close(done)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+	case <-timer.C:
+	}
+}
@@ -1,43 +1,119 @@
 package log
 
-import (
-	"os"
-	"runtime/debug"
-)
+import "sort"
 
 type Fields map[string]interface{}
 
+// sortedKeys returns sorted keys of fields
+func sortedKeys(fields Fields) []string {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// mergeFields returns a copy of base with fields added in the order of keys, fields
+// are added in sorted order if keys is nil. Keys already in base keep their order,
+// and base is returned directly if fields is empty.
+func mergeFields(base Fields, baseKeys []string, fields Fields, keys []string) (Fields, []string) {
+	if len(fields) == 0 {
+		return base, baseKeys
+	}
+
+	if keys == nil {
+		keys = sortedKeys(fields)
+	}
+
+	merged := make(Fields, len(base)+len(fields))
+	for key, value := range base {
+		merged[key] = value
+	}
+
+	mergedKeys := make([]string, len(baseKeys), len(baseKeys)+len(keys))
+	copy(mergedKeys, baseKeys)
+	for _, key := range keys {
+		if _, ok := merged[key]; !ok {
+			mergedKeys = append(mergedKeys, key)
+		}
+		merged[key] = fields[key]
+	}
+
+	return merged, mergedKeys
+}
+
+// FieldKeys returns keys of fields in a stable order, keys in pinned come first in
+// their order, then the others in the order they were added if known, and the rest
+// in sorted order.
+func (m *Message) FieldKeys(pinned ...string) []string {
+	if len(m.Fields) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(m.Fields))
+	seen := make(map[string]struct{}, len(m.Fields))
+	add := func(key string) {
+		if _, ok := m.Fields[key]; !ok {
+			return
+		}
+
+		if _, ok := seen[key]; !ok {
+			seen[key] = struct{}{}
+			keys = append(keys, key)
+		}
+	}
+
+	for _, key := range pinned {
+		add(key)
+	}
+
+	for _, key := range m.keys {
+		add(key)
+	}
+
+	if len(keys) < len(m.Fields) {
+		for _, key := range sortedKeys(m.Fields) {
+			add(key)
+		}
+	}
+
+	return keys
+}
+
 func (f Fields) Trace(args ...interface{}) {
-	logctx.log(LevelTrace, formatLogMessage(args...), f)
+	logctx.log(LevelTrace, formatLogMessage(args...), f, nil)
 }
 
 func (f Fields) Debug(args ...interface{}) {
-	logctx.log(LevelDebug, formatLogMessage(args...), f)
+	logctx.log(LevelDebug, formatLogMessage(args...), f, nil)
 }
 
 func (f Fields) Verbose(args ...interface{}) {
-	logctx.log(LevelVerbose, formatLogMessage(args...), f)
+	logctx.log(LevelVerbose, formatLogMessage(args...), f, nil)
 }
 
 func (f Fields) Info(args ...interface{}) {
-	logctx.log(LevelInfo, formatLogMessage(args...), f)
+	logctx.log(LevelInfo, formatLogMessage(args...), f, nil)
 }
 
 func (f Fields) Warning(args ...interface{}) {
-	logctx.log(LevelWarn, formatLogMessage(args...), f)
+	logctx.log(LevelWarn, formatLogMessage(args...), f, nil)
 }
 
 func (f Fields) Error(args ...interface{}) {
-	logctx.log(LevelError, formatLogMessage(args...), f)
+	logctx.log(LevelError, formatLogMessage(args...), f, nil)
 }
 
 func (f Fields) Fatal(args ...interface{}) {
-	logctx.log(LevelFatal, formatLogMessage(args...), f)
-	os.Exit(1)
+	logctx.log(LevelFatal, formatLogMessage(args...), f, nil)
 }
 
 func (f Fields) Panic(args ...interface{}) {
-	logctx.log(LevelPanic, formatLogMessage(args...), f)
-	debug.PrintStack()
-	os.Exit(1)
+	logctx.log(LevelPanic, formatLogMessage(args...), f, nil)
 }
@@ -10,45 +10,123 @@ import (
 	"io/fs"
 	"math/rand"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"regexp"
 	"runtime/debug"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"syscall"
 	"time"
 )
 
 const (
 	RotateByDuration = "RotateByDuration"
 	RotateBySize     = "RotateBySize"
+	RotateHourly     = "RotateHourly"   // rotate at the top of every hour
+	RotateDaily      = "RotateDaily"    // rotate at midnight
+	RotateWeekly     = "RotateWeekly"   // rotate at midnight of the rotate weekday
+	RotateExternal   = "RotateExternal" // rotated by external tools like logrotate, log file is reopened instead
 
 	SweepByFileCount = "SweepByFileCount"
 	SweepByInterval  = "SweepByInterval"
 
+	CompressTgz  = "tgz"  // archive rotated log file to name.tgz
+	CompressGzip = "gz"   // compress rotated log file to name.log.gz
+	CompressNone = "none" // keep rotated log file as it is
+
 	DefaultRotatePolicy   = RotateByDuration
 	DefaultSweepPolicy    = SweepByFileCount
 	DefaultRotateFileSize = 50 << 20           // rotate log file every 50M
 	DefaultRotateDuration = 24 * time.Hour     // rotate log file every 24 hours
 	DefaultSweepInterval  = 7 * 24 * time.Hour // sweep log file 7 days before
 	DefaultSweepFileCount = 5
+	DefaultCompression    = CompressTgz
+	DefaultCompressLevel  = flate.BestCompression
 
 	defaultCacheSize         = 2 << 10
 	defaultLogfileTimeLayout = "2006-01-02T150405"
 )
 
 var (
-	defaultFnFormatter = func() string {
-		return fmt.Sprintf("%s-%s.log", filepath.Base(os.Args[0]), time.Now().Format(defaultLogfileTimeLayout))
-	}
-	defaultFnRegex = regexp.MustCompile(fmt.Sprintf("%s-\\d+-\\d+-\\d+T\\d+\\.tgz", filepath.Base(os.Args[0])))
+	defaultFnFormatter = fnFormatter(filepath.Base(os.Args[0]))
+	defaultFnRegex     = fnRegex(filepath.Base(os.Args[0]))
 )
 
-func RotatePolicy(policy string) Option {
+// fnFormatter returns the default log filename formatter with specified prefix
+func fnFormatter(prefix string) func() string {
+	return func() string {
+		return fmt.Sprintf("%s-%s.log", prefix, time.Now().Format(defaultLogfileTimeLayout))
+	}
+}
+
+// fnRegex returns regex matches log filenames (without extension) created by fnFormatter
+// and uniqueFilename
+func fnRegex(prefix string) *regexp.Regexp {
+	return regexp.MustCompile(fmt.Sprintf("^%s-\\d+-\\d+-\\d+T\\d+(\\.\\d+)?$", regexp.QuoteMeta(prefix)))
+}
+
+// uniqueFilename returns filename, or filename with a sequence number before
+// extension if it exists in dir, e.g. when log file is rotated by size more than
+// once in a second
+func uniqueFilename(dir, filename string) string {
+	ext := filepath.Ext(filename)
+	stem := strings.TrimSuffix(filename, ext)
+
+	for seq := 1; exists(dir, filename); seq++ {
+		filename = fmt.Sprintf("%s.%d%s", stem, seq, ext)
+	}
+
+	return filename
+}
+
+// exists reports whether log file filename or its compressed file exists in dir
+func exists(dir, filename string) bool {
+	stem := strings.TrimSuffix(filename, filepath.Ext(filename))
+	for _, name := range []string{filename, stem + ".tgz", filename + ".gz"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RotatePolicy sets policies of rotating log file, RotateBySize can be combined with
+// one of the time based policies, log file is rotated on whichever comes first, e.g.
+// RotatePolicy(RotateDaily, RotateBySize).
+//
+// With RotateExternal, other policies are ignored, file logger writes to a fixed log
+// file, which is named by the pattern of Path or the program name like "app.log",
+// and reopens it on signals of ReopenSignal or when it's renamed or deleted.
+func RotatePolicy(policies ...string) Option {
 	return func(logger Logger) {
 		if f, ok := logger.(*file); ok {
-			f.rotatePolicy = policy
+			f.rotatePolicy, f.rotateBySize = "", false
+			for _, policy := range policies {
+				if policy == RotateBySize {
+					f.rotateBySize = true
+				} else if f.rotatePolicy != RotateExternal {
+					f.rotatePolicy = policy
+				}
+			}
+
+			if f.rotatePolicy == RotateExternal {
+				f.rotateBySize = false
+			}
+		}
+	}
+}
+
+// ReopenSignal sets signals on which file logger reopens its log file with
+// RotateExternal policy, default SIGHUP
+func ReopenSignal(signals ...os.Signal) Option {
+	return func(logger Logger) {
+		if f, ok := logger.(*file); ok {
+			f.reopenSignals = signals
 		}
 	}
 }
@@ -61,6 +139,25 @@ func RotateDuration(duration time.Duration) Option {
 	}
 }
 
+// RotateLocation sets time zone of rotation boundaries, e.g. midnight of RotateDaily,
+// default local time
+func RotateLocation(location *time.Location) Option {
+	return func(logger Logger) {
+		if f, ok := logger.(*file); ok && location != nil {
+			f.rotateLocation = location
+		}
+	}
+}
+
+// RotateWeekday sets the day RotateWeekly rotates log file at, default Monday
+func RotateWeekday(weekday time.Weekday) Option {
+	return func(logger Logger) {
+		if f, ok := logger.(*file); ok {
+			f.rotateWeekday = weekday
+		}
+	}
+}
+
 func RotateFileSize(size int64) Option {
 	return func(logger Logger) {
 		if f, ok := logger.(*file); ok {
@@ -69,6 +166,25 @@ func RotateFileSize(size int64) Option {
 	}
 }
 
+// Compression sets how rotated log files are compressed, CompressTgz, CompressGzip or
+// CompressNone, files are compressed in background without blocking logging
+func Compression(codec string) Option {
+	return func(logger Logger) {
+		if f, ok := logger.(*file); ok {
+			f.compression = codec
+		}
+	}
+}
+
+// CompressLevel sets level of compression, from flate.BestSpeed to flate.BestCompression
+func CompressLevel(level int) Option {
+	return func(logger Logger) {
+		if f, ok := logger.(*file); ok {
+			f.compressLevel = level
+		}
+	}
+}
+
 func SweepPolicy(policy string) Option {
 	return func(logger Logger) {
 		if f, ok := logger.(*file); ok {
@@ -93,13 +209,24 @@ func SweepFileCount(count int) Option {
 	}
 }
 
+// Path sets directory of log files and an optional filename pattern, in which
+// strftime-style tokens are replaced when log file is created:
+//
+//	%Y %y %m %d %H %M %S  year, 2-digit year, month, day, hour, minute and second
+//	%{hostname} %{pid}    hostname and process id
+//	%{seq}                the smallest sequence number from 1 makes filename unique
+//	%%                    a literal '%'
+//	*                     a random number
+//
+// e.g. Path("logs", "app-%Y%m%d-%{seq}.log"), ".log" is appended if pattern has no
+// such suffix. Time tokens are in time zone set by RotateLocation.
 func Path(path string, patterns ...string) Option {
 	return func(l Logger) {
 		if f, ok := l.(*file); ok {
 			f.path = path
 			if len(patterns) == 0 {
-				f.format = defaultFnFormatter
-				f.fnregex = defaultFnRegex
+				f.format = nil
+				f.fnregex = nil
 				return
 			}
 
@@ -113,37 +240,139 @@ func Path(path string, patterns ...string) Option {
 				}
 			}
 
-			var (
-				prefix string
-				suffix string
-			)
-
-			index := strings.LastIndexByte(pattern, '*')
-			if index >= 0 {
-				prefix = pattern[:index]
-				suffix = pattern[index+1:]
-			} else {
-				prefix = pattern
+			if !strings.HasSuffix(pattern, ".log") {
+				pattern = pattern + ".log"
 			}
 
-			if !strings.HasSuffix(suffix, ".log") {
-				suffix = suffix + ".log"
-			}
-
-			f.fnregex = regexp.MustCompile(fmt.Sprintf("%s\\d+%s\\.tgz", prefix, strings.TrimSuffix(suffix, "log")))
+			format, regex, sequenced := compilePattern(strings.TrimSuffix(pattern, ".log"))
+			f.fnregex = regex
 			f.format = func() string {
-				rand.Seed(time.Now().Unix())
-				try := 0
-				for try < 100 {
-					filename := fmt.Sprintf("%s%d%s", prefix, rand.Int31(), suffix)
-					if _, err := os.Stat(filepath.Join(f.path, filename)); err != nil && os.IsNotExist(err) {
+				now := time.Now().In(f.rotateLocation)
+				if !sequenced {
+					return format(now, 0) + ".log"
+				}
+
+				for seq := 1; ; seq++ {
+					if filename := format(now, seq) + ".log"; !exists(f.path, filename) {
 						return filename
 					}
-					try++
 				}
+			}
+		}
+	}
+}
+
+// compilePattern compiles filename pattern of Path to a function formats filename
+// with time and sequence number, and regex matches filenames formatted by it or
+// made unique by uniqueFilename, sequenced reports whether pattern has %{seq}
+func compilePattern(pattern string) (format func(now time.Time, seq int) string, regex *regexp.Regexp, sequenced bool) {
+	var (
+		segments []func(sb *strings.Builder, now time.Time, seq int)
+		expr     strings.Builder
+		random   = rand.New(rand.NewSource(time.Now().UnixNano()))
+	)
+
+	literal := func(text string) {
+		segments = append(segments, func(sb *strings.Builder, _ time.Time, _ int) {
+			sb.WriteString(text)
+		})
+		expr.WriteString(regexp.QuoteMeta(text))
+	}
+
+	layout := func(layout, re string) {
+		segments = append(segments, func(sb *strings.Builder, now time.Time, _ int) {
+			sb.WriteString(now.Format(layout))
+		})
+		expr.WriteString(re)
+	}
+
+	expr.WriteByte('^')
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] == '*' {
+			segments = append(segments, func(sb *strings.Builder, _ time.Time, _ int) {
+				sb.WriteString(strconv.FormatInt(int64(random.Int31()), 10))
+			})
+			expr.WriteString(`\d+`)
+			continue
+		}
+
+		if pattern[i] != '%' || i+1 == len(pattern) {
+			literal(pattern[i : i+1])
+			continue
+		}
 
-				return ""
+		i++
+		switch pattern[i] {
+		case 'Y':
+			layout("2006", `\d{4}`)
+		case 'y':
+			layout("06", `\d{2}`)
+		case 'm':
+			layout("01", `\d{2}`)
+		case 'd':
+			layout("02", `\d{2}`)
+		case 'H':
+			layout("15", `\d{2}`)
+		case 'M':
+			layout("04", `\d{2}`)
+		case 'S':
+			layout("05", `\d{2}`)
+		case '%':
+			literal("%")
+		case '{':
+			end := strings.IndexByte(pattern[i:], '}')
+			if end < 0 {
+				literal(pattern[i-1:])
+				i = len(pattern)
+				break
 			}
+
+			switch name := pattern[i+1 : i+end]; name {
+			case "hostname":
+				hostname, err := os.Hostname()
+				if err != nil {
+					hostname = "localhost"
+				}
+				literal(hostname)
+			case "pid":
+				segments = append(segments, func(sb *strings.Builder, _ time.Time, _ int) {
+					sb.WriteString(strconv.Itoa(os.Getpid()))
+				})
+				expr.WriteString(`\d+`)
+			case "seq":
+				sequenced = true
+				segments = append(segments, func(sb *strings.Builder, _ time.Time, seq int) {
+					sb.WriteString(strconv.Itoa(seq))
+				})
+				expr.WriteString(`\d+`)
+			default:
+				literal("%{" + name + "}")
+			}
+			i += end
+		default:
+			literal(pattern[i-1 : i+1])
+		}
+	}
+	expr.WriteString(`(\.\d+)?$`)
+
+	format = func(now time.Time, seq int) string {
+		var sb strings.Builder
+		for _, segment := range segments {
+			segment(&sb, now, seq)
+		}
+		return sb.String()
+	}
+
+	return format, regexp.MustCompile(expr.String()), sequenced
+}
+
+// Symlink makes file logger keep a symbolic link named name in log directory points
+// to the active log file, or a hard link if symbolic link is not supported, e.g.
+// Symlink("current.log")
+func Symlink(name string) Option {
+	return func(l Logger) {
+		if f, ok := l.(*file); ok {
+			f.symlink = name
 		}
 	}
 }
@@ -153,58 +382,108 @@ func Path(path string, patterns ...string) Option {
 // month's log data and will remove older log files, and you can change the rotate
 // time duration and cached log interval
 type file struct {
-	level          Level
+	name           string
+	level          uint32 // Level, accessed atomically
 	path           string
 	sweepPolicy    string
 	sweepInterval  time.Duration
 	sweepFileCount int
-	rotatePolicy   string        // RotateByDuration, RotateBySize
+	rotatePolicy   string // RotateByDuration, RotateHourly, RotateDaily, RotateWeekly or empty
+	rotateBySize   bool
 	rotateDuration time.Duration // sweep log files with 'interval' days before
+	rotateLocation *time.Location
+	rotateWeekday  time.Weekday
+	rotateAt       time.Time // wall clock time of next rotation, zero if not rotated by time
+	rotatedAt      time.Time // wall clock time of last rotation
+	reopenSignals  []os.Signal
 	rotateFileSize int64
 	filesize       int64
 	filename       string
 	file           *os.File
 	format         func() string
 	fnregex        *regexp.Regexp
+	symlink        string // name of link to the active log file, empty if disabled
 	buf            *bytes.Buffer
-	formatter      Formatter
+	formatter      formatterValue
+	redactors      []Redactor
 	messages       chan *Message
+	syncs          chan chan error
 	closeNotify    chan struct{}
+	done           chan struct{}
+	closeErr       error // error of flushing and closing file, set before done closed
+	compression    string
+	compressLevel  int
+	archives       chan struct{} // requests of archiving when log files were rotated, buffer size 1
+	active         atomic.Value  // name of the active log file, read by archive worker
+	archived       chan struct{} // closed when archive worker exits
+	fsync          bool
 	closed         uint32
 }
 
 // NewFileLogger creates a file logger implementation
 func NewFileLogger(level Level, options ...Option) Logger {
 	f := &file{
-		level:          level,
+		name:           File,
+		level:          uint32(level),
 		path:           "",
 		filename:       "",
 		file:           nil,
-		formatter:      new(TextFormatter),
 		rotatePolicy:   DefaultRotatePolicy,
 		rotateDuration: DefaultRotateDuration,
 		rotateFileSize: DefaultRotateFileSize,
+		rotateLocation: time.Local,
+		rotateWeekday:  time.Monday,
+		reopenSignals:  []os.Signal{syscall.SIGHUP},
 		sweepPolicy:    DefaultSweepPolicy,
 		sweepFileCount: DefaultSweepFileCount,
 		sweepInterval:  DefaultSweepInterval,
+		compression:    DefaultCompression,
+		compressLevel:  DefaultCompressLevel,
 		filesize:       0,
-		format:         defaultFnFormatter,
-		fnregex:        defaultFnRegex,
 		buf:            bytes.NewBuffer(make([]byte, 0, defaultCacheSize)),
 		messages:       make(chan *Message, BufferCapacity),
+		syncs:          make(chan chan error),
 		closeNotify:    make(chan struct{}),
+		done:           make(chan struct{}),
 	}
 
+	f.formatter.Store(new(TextFormatter))
+
 	for _, option := range options {
 		option(f)
 	}
 
+	if f.format == nil {
+		// loggers named by user write to their own log files
+		prefix := filepath.Base(os.Args[0])
+		if f.name == File {
+			f.format, f.fnregex = defaultFnFormatter, defaultFnRegex
+		} else {
+			prefix += "-" + f.name
+			f.format, f.fnregex = fnFormatter(prefix), fnRegex(prefix)
+		}
+
+		if f.rotatePolicy == RotateExternal {
+			f.filename = prefix + ".log"
+		}
+	}
+
+	if f.rotatePolicy == RotateExternal && f.filename == "" {
+		f.filename = f.format()
+	}
+
 	if len(f.path) > 0 {
 		os.MkdirAll(f.path, 0770)
 	} else {
 		f.path = "."
 	}
 
+	if f.rotatePolicy != RotateExternal {
+		f.archives = make(chan struct{}, 1)
+		f.archived = make(chan struct{})
+		go f.archive()
+	}
+
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go f.run(wg.Done)
@@ -216,100 +495,340 @@ func NewFileLogger(level Level, options ...Option) Logger {
 
 func (f *file) run(ready func()) {
 	var (
-		elapse time.Duration
-		err    error
+		err      error
+		interval = time.Minute
+		reopen   chan os.Signal
 	)
 
-	if err = f.rotate(); err != nil {
+	if f.rotatePolicy == RotateExternal {
+		err = f.reopen()
+	} else {
+		err = f.rotate()
+	}
+
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "%v", err)
 		debug.PrintStack()
 		os.Exit(1)
 	}
 
+	if f.rotatePolicy == RotateExternal {
+		// check whether log file is rotated externally more often
+		interval = time.Second
+		if len(f.reopenSignals) > 0 {
+			reopen = make(chan os.Signal, 1)
+			signal.Notify(reopen, f.reopenSignals...)
+			defer signal.Stop(reopen)
+		}
+	}
+
 	ready()
 
-	ticker := time.NewTicker(time.Minute)
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case msg := <-f.messages:
 			f.write(msg)
-		case <-ticker.C:
-			elapse += time.Minute
-			if (f.rotatePolicy == RotateBySize && f.filesize >= f.rotateFileSize) ||
-				(f.rotatePolicy == RotateByDuration && elapse >= f.rotateDuration) {
+		case ack := <-f.syncs:
+			f.drain()
+			if err = f.flush(); err == nil && f.fsync && f.file != nil {
+				err = f.file.Sync()
+			}
+			ack <- err
+		case <-reopen:
+			if err = f.reopen(); err != nil {
+				fmt.Fprintf(os.Stderr, "reopen log file failed, %v\n", err)
+			}
+		case now := <-ticker.C:
+			if f.rotatePolicy == RotateExternal {
+				if f.moved() {
+					if err = f.reopen(); err != nil {
+						fmt.Fprintf(os.Stderr, "reopen log file failed, %v\n", err)
+					}
+				}
+				break
+			}
+
+			// compare wall clock, monotonic clock stops while system is sleeping
+			now = now.Round(0)
+			if now.Before(f.rotatedAt) {
+				// wall clock jumped backward, the deadline may be too far away
+				f.rotateAt = f.nextRotation(now)
+			}
+
+			if f.rotateDue(now) {
 				_ = f.rotate()
-				elapse = 0
-				go f.sweep()
 			}
 		case <-f.closeNotify:
+			f.drain()
+			f.closeErr = f.flush()
+			if f.file != nil {
+				if err = f.file.Close(); f.closeErr == nil {
+					f.closeErr = err
+				}
+			}
+
+			if f.archives != nil {
+				close(f.archives)
+			}
+			close(f.done)
 			return
 		}
 	}
 }
 
-func (f *file) compress() error {
-	var (
-		gzfilename string
-		gzw        *gzip.Writer
-		gzfile     *os.File
-		lfile      *os.File
-		tw         *tar.Writer
-		info       os.FileInfo
-		header     *tar.Header
-		e          error
-	)
+// reopen flushes and closes log file, and opens the log file of the same name, which
+// may be a new file after external rotation
+func (f *file) reopen() (err error) {
+	if f.file != nil {
+		f.flush()
+		f.file.Close()
+		f.file = nil
+	}
 
-	return filepath.WalkDir(f.path, func(path string, d fs.DirEntry, err error) error {
-		if d.IsDir() || f.filename == d.Name() {
-			return nil
+	if f.file, err = os.OpenFile(filepath.Join(f.path, f.filename), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0660); err != nil {
+		return err
+	}
+
+	f.filesize = 0
+	if info, e := f.file.Stat(); e == nil {
+		f.filesize = info.Size()
+	}
+
+	return nil
+}
+
+// moved reports whether log file was renamed or deleted by external rotation, size
+// of log file is reset if it was truncated, e.g. by logrotate's copytruncate
+func (f *file) moved() bool {
+	if f.file == nil {
+		return true
+	}
+
+	info, err := os.Stat(filepath.Join(f.path, f.filename))
+	if err != nil {
+		return true
+	}
+
+	current, err := f.file.Stat()
+	if err != nil || !os.SameFile(info, current) {
+		return true
+	}
+
+	if info.Size() < f.filesize {
+		f.filesize = info.Size()
+	}
+
+	return false
+}
+
+// nextRotation returns the first rotation boundary after t, boundaries of
+// RotateByDuration are aligned to midnight if duration divides a day, or to Unix
+// epoch otherwise. Zero time is returned if log file is not rotated by time.
+func (f *file) nextRotation(t time.Time) time.Time {
+	t = t.In(f.rotateLocation)
+	year, month, day := t.Date()
+
+	switch f.rotatePolicy {
+	case RotateHourly:
+		return time.Date(year, month, day, t.Hour()+1, 0, 0, 0, f.rotateLocation)
+	case RotateDaily:
+		return time.Date(year, month, day+1, 0, 0, 0, 0, f.rotateLocation)
+	case RotateWeekly:
+		days := (int(f.rotateWeekday) - int(t.Weekday()) + 7) % 7
+		if days == 0 {
+			days = 7
+		}
+		return time.Date(year, month, day+days, 0, 0, 0, 0, f.rotateLocation)
+	case RotateByDuration:
+		if f.rotateDuration <= 0 {
+			return time.Time{}
 		}
 
-		if strings.HasSuffix(path, ".log") {
-			if info, e = d.Info(); e != nil {
-				return e
-			}
+		if (24*time.Hour)%f.rotateDuration != 0 {
+			d := int64(f.rotateDuration)
+			return time.Unix(0, (t.UnixNano()/d+1)*d).In(f.rotateLocation)
+		}
 
-			gzfilename = path[:len(path)-4] + ".tgz"
-			if gzfile, e = os.Create(gzfilename); e != nil {
-				return e
-			}
-			defer gzfile.Close()
+		// boundaries are computed in wall clock, so they are not shifted by daylight
+		// saving time
+		elapsed := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+			time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+		next := (elapsed/f.rotateDuration + 1) * f.rotateDuration
+		return time.Date(year, month, day, int(next/time.Hour), int(next%time.Hour/time.Minute),
+			int(next%time.Minute/time.Second), int(next%time.Second), f.rotateLocation)
+	}
 
-			if lfile, e = os.Open(path); e != nil {
-				return e
-			}
-			defer lfile.Close()
+	return time.Time{}
+}
 
-			if gzw, e = gzip.NewWriterLevel(gzfile, flate.BestCompression); e != nil {
-				return e
-			}
-			defer func() {
-				gzw.Flush()
-				gzw.Close()
-			}()
+// rotateDue reports whether wall clock time t reaches the rotation deadline
+func (f *file) rotateDue(t time.Time) bool {
+	return !f.rotateAt.IsZero() && !t.Round(0).Before(f.rotateAt)
+}
 
-			tw = tar.NewWriter(gzw)
-			defer tw.Close()
+// drain writes messages remained in channel
+func (f *file) drain() {
+	for {
+		select {
+		case msg := <-f.messages:
+			f.write(msg)
+		default:
+			return
+		}
+	}
+}
 
-			if header, e = tar.FileInfoHeader(info, ""); e != nil {
-				return e
-			}
+// archive compresses rotated log files and sweeps old ones in background, until
+// archives is closed. Log files are compressed to temporary files renamed when
+// completed, temporary files left by crash are removed and log files are compressed
+// again on the next start.
+func (f *file) archive() {
+	defer close(f.archived)
+
+	for range f.archives {
+		if err := f.compress(); err != nil {
+			f.Write(&Message{
+				Level:     LevelError,
+				Message:   err.Error(),
+				Timestamp: time.Now(),
+			})
+		}
 
-			if e = tw.WriteHeader(header); e != nil {
-				return e
-			}
+		f.sweep()
+	}
+}
 
-			if _, e = io.Copy(tw, lfile); e != nil {
-				return e
-			}
+// isActive reports whether filename is the active log file, a file is never active
+// again once log file is rotated, since filenames are unique
+func (f *file) isActive(filename string) bool {
+	active, _ := f.active.Load().(string)
+	return filename == active
+}
+
+// archiveExt returns extension of rotated log files
+func (f *file) archiveExt() string {
+	switch f.compression {
+	case CompressGzip:
+		return ".log.gz"
+	case CompressNone:
+		return ".log"
+	default:
+		return ".tgz"
+	}
+}
+
+// compress compresses log files except the active one, and removes temporary files
+// of incomplete compression
+func (f *file) compress() error {
+	var errs multiError
+
+	ext := f.archiveExt()
+	filepath.WalkDir(f.path, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return fs.SkipDir
+		}
+
+		if path == f.path {
+			return nil
+		}
 
+		if d.IsDir() {
+			return fs.SkipDir
+		}
+
+		switch name := d.Name(); {
+		case f.owns(name, ext+".tmp"):
 			os.Remove(path)
+		case f.compression != CompressNone && !f.isActive(name) && f.owns(name, ".log"):
+			if err = f.compressFile(path, strings.TrimSuffix(path, ".log")+ext); err != nil {
+				errs = append(errs, err)
+			}
 		}
 
 		return nil
 	})
+
+	return errs.err()
+}
+
+// compressFile compresses log file at path to target, and removes the log file
+func (f *file) compressFile(path, target string) (err error) {
+	var (
+		tmp = target + ".tmp"
+		in  *os.File
+		out *os.File
+		gzw *gzip.Writer
+		w   io.Writer
+	)
+
+	if in, err = os.Open(path); err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if out, err = os.Create(tmp); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			out.Close()
+			os.Remove(tmp)
+		}
+	}()
+
+	if gzw, err = gzip.NewWriterLevel(out, f.compressLevel); err != nil {
+		return err
+	}
+	w = gzw
+
+	var tw *tar.Writer
+	if f.compression != CompressGzip {
+		var (
+			info   os.FileInfo
+			header *tar.Header
+		)
+
+		if info, err = in.Stat(); err != nil {
+			return err
+		}
+
+		if header, err = tar.FileInfoHeader(info, ""); err != nil {
+			return err
+		}
+
+		tw = tar.NewWriter(gzw)
+		if err = tw.WriteHeader(header); err != nil {
+			return err
+		}
+		w = tw
+	}
+
+	if _, err = io.Copy(w, in); err != nil {
+		return err
+	}
+
+	if tw != nil {
+		if err = tw.Close(); err != nil {
+			return err
+		}
+	}
+
+	if err = gzw.Close(); err != nil {
+		return err
+	}
+
+	if err = out.Close(); err != nil {
+		return err
+	}
+
+	if err = os.Rename(tmp, target); err != nil {
+		return err
+	}
+
+	return os.Remove(path)
 }
 
 func (f *file) rotate() error {
@@ -318,29 +837,63 @@ func (f *file) rotate() error {
 		err   error
 	)
 
+	now := time.Now().Round(0)
+	f.rotatedAt, f.rotateAt = now, f.nextRotation(now)
+
 	if f.file != nil {
+		f.flush()
 		f.file.Close()
 	}
-
-	// compress old log files
-	if err = f.compress(); err != nil {
-		f.write(&Message{
-			Level:     LevelError,
-			Message:   err.Error(),
-			Timestamp: time.Now(),
-		})
-	}
-
 	f.filesize = 0
+
 	f.filename = f.format()
 	if f.filename == "" {
 		f.filename = defaultFnFormatter()
 	}
+	f.filename = uniqueFilename(f.path, f.filename)
+	f.active.Store(f.filename)
 
 	fname = f.path + "/" + f.filename
-	f.file, err = os.OpenFile(fname, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0660)
+	if f.file, err = os.OpenFile(fname, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0660); err != nil {
+		return err
+	}
 
-	return err
+	if f.symlink != "" {
+		if err = f.link(); err != nil {
+			f.write(&Message{
+				Level:     LevelError,
+				Message:   err.Error(),
+				Timestamp: time.Now(),
+			})
+		}
+	}
+
+	// compress and sweep old log files in background after the symlink is updated,
+	// no more request is needed if there is a pending one
+	if f.archives != nil {
+		select {
+		case f.archives <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// link points symlink to the active log file, the link is replaced by rename, so
+// readers never find it missing
+func (f *file) link() error {
+	link := filepath.Join(f.path, f.symlink)
+	tmp := link + ".tmp"
+	os.Remove(tmp)
+
+	if err := os.Symlink(f.filename, tmp); err != nil {
+		if err = os.Link(filepath.Join(f.path, f.filename), tmp); err != nil {
+			return err
+		}
+	}
+
+	return os.Rename(tmp, link)
 }
 
 type logfile struct {
@@ -362,9 +915,11 @@ func (bts byTimestamp) Swap(i, j int) {
 	bts[i], bts[j] = bts[j], bts[i]
 }
 
+// sweep removes rotated log files except the active one by sweep policy
 func (f *file) sweep() {
 	var files byTimestamp
 
+	ext := f.archiveExt()
 	tm := time.Now()
 	filepath.WalkDir(f.path, func(path string, entry fs.DirEntry, err error) error {
 		if err != nil {
@@ -379,10 +934,10 @@ func (f *file) sweep() {
 			return fs.SkipDir
 		}
 
-		if f.fnregex.MatchString(path) || (f.fnregex != defaultFnRegex && defaultFnRegex.MatchString(path)) {
+		if !f.isActive(entry.Name()) && f.owns(entry.Name(), ext) {
 			if fi, err := entry.Info(); err == nil {
 				if f.sweepPolicy == SweepByInterval {
-					if tm.Before(fi.ModTime().Add(f.sweepInterval)) {
+					if tm.After(fi.ModTime().Add(f.sweepInterval)) {
 						os.Remove(path)
 					}
 				} else if f.sweepPolicy == SweepByFileCount {
@@ -408,13 +963,35 @@ func (f *file) sweep() {
 	}
 }
 
+// owns reports whether filename with extension ext was created by this logger
+func (f *file) owns(filename, ext string) bool {
+	if !strings.HasSuffix(filename, ext) || filename == f.symlink {
+		return false
+	}
+
+	filename = strings.TrimSuffix(filename, ext)
+	return f.fnregex.MatchString(filename) || (f.name == File && defaultFnRegex.MatchString(filename))
+}
+
 func (f *file) write(msg *Message) {
 	if msg == nil {
 		return
 	}
 
+	// rotate before writing the first message after deadline, so messages are
+	// written to files of their periods even if ticker is late
+	if f.rotateDue(msg.Timestamp) {
+		_ = f.rotate()
+	}
+
 	msgstr := f.Format(msg)
 
+	// rotate before log file exceeds the size limit, only a message larger than the
+	// limit makes a log file exceed it
+	if size := f.filesize + int64(f.buf.Len()); f.rotateBySize && size > 0 && size+int64(len(msgstr))+1 > f.rotateFileSize {
+		_ = f.rotate()
+	}
+
 	if len(msgstr)+f.buf.Len() >= defaultCacheSize && f.file != nil {
 		n, _ := f.file.Write(f.buf.Bytes())
 		f.filesize += int64(n)
@@ -425,20 +1002,50 @@ func (f *file) write(msg *Message) {
 	f.buf.WriteByte('\n')
 }
 
-func (f *file) flush() {
+func (f *file) flush() (err error) {
 	if f.file != nil {
-		n, _ := f.file.Write(f.buf.Bytes())
+		var n int
+		n, err = f.file.Write(f.buf.Bytes())
 		f.filesize += int64(n)
 		f.buf.Reset()
 	}
+
+	return err
+}
+
+// Fsync makes file logger commit log file to disk when it's flushed
+func Fsync(enabled bool) Option {
+	return func(l Logger) {
+		if f, ok := l.(*file); ok {
+			f.fsync = enabled
+		}
+	}
+}
+
+// Flush writes pending messages and cached data to file, and commits file to disk
+// if fsync is enabled
+func (f *file) Flush() error {
+	ack := make(chan error, 1)
+
+	select {
+	case f.syncs <- ack:
+	case <-f.done:
+		return nil
+	}
+
+	return <-ack
 }
 
 func (f *file) Name() string {
-	return File
+	return f.name
 }
 
 func (f *file) Level() Level {
-	return f.level
+	return Level(atomic.LoadUint32(&f.level))
+}
+
+func (f *file) SetLevel(level Level) {
+	atomic.StoreUint32(&f.level, uint32(level))
 }
 
 func (f *file) Write(msg *Message) {
@@ -446,32 +1053,34 @@ func (f *file) Write(msg *Message) {
 		return
 	}
 
-	f.messages <- msg
+	select {
+	case f.messages <- msg:
+	case <-f.closeNotify:
+	}
 }
 
+// Close stops accepting messages, and waits until pending messages were written
 func (f *file) Close() error {
 	if !atomic.CompareAndSwapUint32(&f.closed, 0, 1) {
 		return nil
 	}
 
-	<-time.After(time.Second)
-
 	close(f.closeNotify)
-	close(f.messages)
+	<-f.done
 
-	f.flush()
-
-	if f.file != nil {
-		f.file.Close()
+	if f.archived != nil {
+		<-f.archived
 	}
 
-	return nil
+	return f.closeErr
 }
 
 func (f *file) Format(msg *Message) string {
-	if f.formatter == nil {
-		f.formatter = new(TextFormatter)
+	msg = redact(msg, f.redactors)
+	formatter := f.formatter.Load()
+	if formatter == nil {
+		formatter = defaultFormatter
 	}
 
-	return f.formatter.Format(msg)
+	return formatter.Format(msg)
 }
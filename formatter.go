@@ -2,33 +2,127 @@ package log
 
 import (
 	"bytes"
-	"encoding/json"
 	"fmt"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
-type TextFormatter struct {
-	bp sync.Pool
+var defaultFormatter Formatter = new(TextFormatter)
+
+// formatterValue holds a logger's formatter, which can be replaced while the
+// logger is writing messages
+type formatterValue struct {
+	v atomic.Value
+}
+
+type formatterHolder struct {
+	Formatter
+}
+
+func (fv *formatterValue) Load() Formatter {
+	if h, ok := fv.v.Load().(formatterHolder); ok {
+		return h.Formatter
+	}
+
+	return nil
 }
 
-func (tf *TextFormatter) acquire() *bytes.Buffer {
-	if v := tf.bp.Get(); v != nil {
+func (fv *formatterValue) Store(formatter Formatter) {
+	fv.v.Store(formatterHolder{formatter})
+}
+
+// bufferPool caches buffers used by formatters
+type bufferPool struct {
+	sync.Pool
+}
+
+func (bp *bufferPool) acquire() *bytes.Buffer {
+	if v := bp.Get(); v != nil {
 		return v.(*bytes.Buffer)
 	}
 
 	return bytes.NewBuffer(make([]byte, 0, 256))
 }
 
-func (tf *TextFormatter) recycle(buf *bytes.Buffer) {
+func (bp *bufferPool) recycle(buf *bytes.Buffer) {
 	buf.Reset()
-	tf.bp.Put(buf)
+	bp.Put(buf)
+}
+
+// Special layouts of TimeFormat, timestamps in these layouts are numbers
+const (
+	TimeUnix      = "unix"      // seconds since Unix epoch
+	TimeUnixMilli = "unixmilli" // milliseconds since Unix epoch
+	TimeElapsed   = "elapsed"   // seconds elapsed since process start, in milliseconds precision
+)
+
+// processStart is the start time of process, with monotonic clock reading
+var processStart = time.Now()
+
+// TimeFormat specifies how formatters write timestamp of message
+type TimeFormat struct {
+	// Layout is a Go time layout, e.g. time.RFC3339Nano, or one of TimeUnix,
+	// TimeUnixMilli and TimeElapsed, formatter's default layout is used if empty
+	Layout string
+
+	// Location converts timestamp to, e.g. time.UTC, timestamp is in local time if nil
+	Location *time.Location
+}
+
+// numeric reports whether timestamp is written as a number
+func (tf *TimeFormat) numeric() bool {
+	switch tf.Layout {
+	case TimeUnix, TimeUnixMilli, TimeElapsed:
+		return true
+	}
+
+	return false
+}
+
+// appendTime appends t to b in layout of tf, or defaultLayout if tf has no layout
+func (tf *TimeFormat) appendTime(b []byte, t time.Time, defaultLayout string) []byte {
+	switch tf.Layout {
+	case TimeUnix:
+		return strconv.AppendInt(b, t.Unix(), 10)
+	case TimeUnixMilli:
+		return strconv.AppendInt(b, t.UnixNano()/int64(time.Millisecond), 10)
+	case TimeElapsed:
+		return strconv.AppendFloat(b, t.Sub(processStart).Seconds(), 'f', 3, 64)
+	}
+
+	if tf.Location != nil {
+		t = t.In(tf.Location)
+	}
+
+	if tf.Layout != "" {
+		return t.AppendFormat(b, tf.Layout)
+	}
+
+	return t.AppendFormat(b, defaultLayout)
+}
+
+// TextFormatter formats message as a line of text, fields are printed in the order
+// they were added if known, otherwise sorted by key
+type TextFormatter struct {
+	// PinnedKeys are keys of fields printed before other fields, e.g. "request_id"
+	PinnedKeys []string
+
+	// Time is format of timestamp, default local time in 2006/01/02 15:04:05.000
+	Time TimeFormat
+
+	bp bufferPool
 }
 
 func (tf *TextFormatter) Format(msg *Message) (message string) {
-	buf := tf.acquire()
+	var (
+		buf = tf.bp.acquire()
+		num [64]byte
+	)
 
-	buf.WriteString(msg.Timestamp.Format(defaultTimelayout))
+	buf.Write(tf.Time.appendTime(num[:0], msg.Timestamp, defaultTimelayout))
 	buf.WriteByte(' ')
 	buf.WriteString(msg.Level.Tag())
 	if msg.Filename != "" && msg.Function != "" {
@@ -47,10 +141,10 @@ func (tf *TextFormatter) Format(msg *Message) (message string) {
 	if len(msg.Fields) > 0 {
 		sep := ""
 		buf.WriteString(" (")
-		for key, value := range msg.Fields {
+		for _, key := range msg.FieldKeys(tf.PinnedKeys...) {
 			buf.WriteString(sep)
 			buf.WriteString(key)
-			buf.WriteString(fmt.Sprintf(" = %v", value))
+			buf.WriteString(fmt.Sprintf(" = %v", msg.Fields[key]))
 			if sep == "" {
 				sep = ", "
 			}
@@ -58,40 +152,143 @@ func (tf *TextFormatter) Format(msg *Message) (message string) {
 		buf.WriteByte(')')
 	}
 
+	if msg.Stack != "" {
+		buf.WriteString("\n\t")
+		buf.WriteString(strings.ReplaceAll(msg.Stack, "\n", "\n\t"))
+	}
+
 	message = buf.String()
-	tf.recycle(buf)
+	tf.bp.recycle(buf)
 
 	return message
 }
 
-type JSONFormatter struct{}
+// Default keys of JSONFormatter
+const (
+	DefaultLevelKey      = "level"
+	DefaultMessageKey    = "message"
+	DefaultTimeKey       = "timestamp"
+	DefaultFilenameKey   = "filename"
+	DefaultFunctionKey   = "function"
+	DefaultLineKey       = "line"
+	DefaultStacktraceKey = "stacktrace"
+)
+
+// conflictPrefix prefixes keys of fields conflict with keys of message
+const conflictPrefix = "fields."
 
-func (jf *JSONFormatter) Format(msg *Message) string {
-	if len(msg.Fields) > 0 {
-		msg.Fields["level"] = msg.Level
-		msg.Fields["message"] = msg.Message
-		msg.Fields["timestamp"] = msg.Timestamp
-
-		if msg.Function != "" && msg.Filename != "" {
-			msg.Fields["line"] = msg.Line
-			msg.Fields["function"] = msg.Function
-			msg.Fields["filename"] = msg.Filename
+// conflictKey returns key of field conflicts with a key of message, which is
+// prefixed by conflictPrefix until it's not a key of other fields, e.g. "level" is
+// "fields.level", or "fields.fields.level" if "fields.level" is also a field
+func conflictKey(key string, fields Fields) string {
+	key = conflictPrefix + key
+	for {
+		if _, ok := fields[key]; !ok {
+			return key
+		}
+		key = conflictPrefix + key
+	}
+}
+
+// JSONFormatter formats message as a JSON object, keys of message can be changed
+// and defaults to DefaultXxxKey if empty. Fields are encoded in the order of
+// Message.FieldKeys, and prefixed by "fields." if they conflict with keys of message.
+// Message.Fields is never modified.
+type JSONFormatter struct {
+	LevelKey      string
+	MessageKey    string
+	TimeKey       string
+	FilenameKey   string
+	FunctionKey   string
+	LineKey       string
+	StacktraceKey string
+
+	// PinnedKeys are keys of fields encoded before other fields
+	PinnedKeys []string
+
+	// Time is format of timestamp, default time.RFC3339Nano, timestamps in special
+	// layouts like TimeUnix are encoded as numbers
+	Time TimeFormat
+
+	bp bufferPool
+}
+
+func keyOr(key, defaultKey string) string {
+	if key == "" {
+		return defaultKey
+	}
+
+	return key
+}
+
+func (jf *JSONFormatter) Format(msg *Message) (message string) {
+	var (
+		buf      = jf.bp.acquire()
+		num      [64]byte
+		reserved = [...]string{
+			keyOr(jf.LevelKey, DefaultLevelKey),
+			keyOr(jf.TimeKey, DefaultTimeKey),
+			keyOr(jf.FilenameKey, DefaultFilenameKey),
+			keyOr(jf.FunctionKey, DefaultFunctionKey),
+			keyOr(jf.LineKey, DefaultLineKey),
+			keyOr(jf.MessageKey, DefaultMessageKey),
+			keyOr(jf.StacktraceKey, DefaultStacktraceKey),
 		}
+	)
+
+	buf.WriteByte('{')
+	writeJSONKey(buf, reserved[0])
+	writeJSONString(buf, msg.Level.String())
+	buf.WriteByte(',')
+	writeJSONKey(buf, reserved[1])
+	if jf.Time.numeric() {
+		buf.Write(jf.Time.appendTime(num[:0], msg.Timestamp, time.RFC3339Nano))
+	} else {
+		buf.WriteByte('"')
+		buf.Write(jf.Time.appendTime(num[:0], msg.Timestamp, time.RFC3339Nano))
+		buf.WriteByte('"')
+	}
+
+	if msg.Filename != "" && msg.Function != "" {
+		buf.WriteByte(',')
+		writeJSONKey(buf, reserved[2])
+		writeJSONString(buf, msg.Filename)
+		buf.WriteByte(',')
+		writeJSONKey(buf, reserved[3])
+		writeJSONString(buf, msg.Function)
+		buf.WriteByte(',')
+		writeJSONKey(buf, reserved[4])
+		buf.Write(strconv.AppendInt(num[:0], int64(msg.Line), 10))
+	}
 
-		data, err := json.Marshal(msg.Fields)
-		if err != nil {
-			fmt.Println(err)
-			return ""
+	buf.WriteByte(',')
+	writeJSONKey(buf, reserved[5])
+	writeJSONString(buf, msg.Message)
+
+	for _, key := range msg.FieldKeys(jf.PinnedKeys...) {
+		value := msg.Fields[key]
+		for _, r := range reserved {
+			if key == r {
+				key = conflictKey(key, msg.Fields)
+				break
+			}
 		}
 
-		return string(data)
+		buf.WriteByte(',')
+		writeJSONKey(buf, key)
+		writeJSONValue(buf, value)
 	}
 
-	data, err := json.Marshal(msg)
-	if err != nil {
-		fmt.Println(err)
-		return ""
+	if msg.Stack != "" {
+		buf.WriteByte(',')
+		writeJSONKey(buf, reserved[6])
+		writeJSONString(buf, msg.Stack)
 	}
 
-	return string(data)
+	buf.WriteByte('}')
+
+	message = buf.String()
+	jf.bp.recycle(buf)
+
+	return message
 }
@@ -0,0 +1,239 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONFormatter(t *testing.T) {
+	fields := Fields{
+		"message": "conflict",
+		"error":   errors.New("failed"),
+		"count":   3,
+		"ratio":   math.Inf(1),
+		"text":    "quote\" \\ \n \x01   \xff",
+		"tags":    []string{"a", "b"},
+		"level":   LevelWarn,
+	}
+
+	msg := &Message{
+		Level:     LevelInfo,
+		Message:   "hello",
+		Timestamp: time.Date(2022, 8, 5, 10, 0, 0, 0, time.UTC),
+		Filename:  "log.go",
+		Function:  "main.main",
+		Line:      42,
+		Fields:    fields,
+	}
+
+	data := (&JSONFormatter{MessageKey: "msg", TimeKey: "ts"}).Format(msg)
+	if len(fields) != 7 {
+		t.Fatalf("fields should not be modified: %v", fields)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal([]byte(data), &decoded); err != nil {
+		t.Fatalf("invalid json %s: %v", data, err)
+	}
+
+	expected := map[string]interface{}{
+		"level":        "Info",
+		"ts":           "2022-08-05T10:00:00Z",
+		"filename":     "log.go",
+		"function":     "main.main",
+		"line":         42.0,
+		"msg":          "hello",
+		"message":      "conflict",
+		"error":        "failed",
+		"count":        3.0,
+		"ratio":        "+Inf",
+		"text":         "quote\" \\ \n \x01   �",
+		"fields.level": "Warn",
+	}
+
+	for key, value := range expected {
+		if decoded[key] != value {
+			t.Fatalf("expect %s = %v, got %v in %s", key, value, decoded[key], data)
+		}
+	}
+
+	if !strings.HasPrefix(data, `{"level":"Info","ts":"2022-08-05T10:00:00Z","filename":"log.go","function":"main.main","line":42,"msg":"hello","count":3`) {
+		t.Fatalf("unexpected json: %s", data)
+	}
+}
+
+func BenchmarkJSONFormatter(b *testing.B) {
+	jf := new(JSONFormatter)
+	msg := &Message{
+		Level:     LevelInfo,
+		Message:   "hello",
+		Timestamp: time.Now(),
+		Fields:    Fields{"name": "derek", "age": 35},
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		jf.Format(msg)
+	}
+}
+
+func TestLogfmtFormatter(t *testing.T) {
+	msg := &Message{
+		Level:     LevelWarn,
+		Message:   "hello world",
+		Timestamp: time.Date(2022, 8, 5, 10, 0, 0, 0, time.UTC),
+		Filename:  "file.go",
+		Line:      42,
+		Fields: Fields{
+			"msg":      "conflict",
+			"user":     "derek",
+			"empty":    "",
+			"quote":    `say "hi"`,
+			"multi":    "a\nb",
+			"bad key":  1,
+			"duration": time.Second,
+			"err":      errors.New("a=b"),
+		},
+	}
+
+	expected := `ts=2022-08-05T10:00:00Z level=warn msg="hello world" caller=file.go:42 user=derek ` +
+		`bad_key=1 duration=1s empty="" err="a=b" fields.msg=conflict multi="a\nb" quote="say \"hi\""`
+
+	if text := (&LogfmtFormatter{PinnedKeys: []string{"user"}}).Format(msg); text != expected {
+		t.Fatalf("expect\n%s\ngot\n%s", expected, text)
+	}
+}
+
+func TestTemplateFormatter(t *testing.T) {
+	msg := &Message{
+		Level:     LevelInfo,
+		Message:   "hello",
+		Timestamp: time.Date(2022, 8, 5, 10, 0, 0, 0, time.FixedZone("CST", 8*3600)),
+		Filename:  "log.go",
+		Line:      42,
+		Fields:    Fields{"user": "derek", "password": "secret", "id": 1},
+	}
+
+	for layout, expected := range map[string]string{
+		"{time:2006-01-02T15:04:05Z07:00|UTC} {level:-5|upper}|{tag} {caller} {msg} {fields:-password}": "2022-08-05T02:00:00Z INFO |[I] log.go:42 hello id=1 user=derek",
+		"{{{level:lower}}} {level:6} {file}:{line} {field:user} {fields:user|password|-password}":       "{info}   Info log.go:42 derek user=derek",
+	} {
+		tf, err := NewTemplateFormatter(layout)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if text := tf.Format(msg); text != expected {
+			t.Fatalf("expect '%s', got '%s'", expected, text)
+		}
+	}
+
+	for _, layout := range []string{"{unknown}", "{msg", "msg}", "{time:2006|Nowhere/City}", "{level:title}", "{field}"} {
+		if _, err := NewTemplateFormatter(layout); err == nil {
+			t.Fatalf("expect error of layout '%s'", layout)
+		}
+	}
+}
+
+func TestTimeFormat(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	msg := &Message{
+		Level:     LevelInfo,
+		Message:   "hello",
+		Timestamp: time.Date(2022, 8, 5, 10, 0, 0, 500000000, time.UTC),
+	}
+
+	for _, c := range []struct {
+		formatter Formatter
+		expected  string
+	}{
+		{&TextFormatter{Time: TimeFormat{Location: shanghai}}, "2022/08/05 18:00:00.500 [I] hello"},
+		{&JSONFormatter{Time: TimeFormat{Layout: TimeUnix}}, `{"level":"Info","timestamp":1659693600,"message":"hello"}`},
+		{&JSONFormatter{Time: TimeFormat{Location: time.UTC}}, `{"level":"Info","timestamp":"2022-08-05T10:00:00.5Z","message":"hello"}`},
+		{&LogfmtFormatter{Time: TimeFormat{Layout: TimeUnixMilli}}, "ts=1659693600500 level=info msg=hello"},
+		{&LogfmtFormatter{Time: TimeFormat{Layout: time.RFC1123, Location: shanghai}}, `ts="Fri, 05 Aug 2022 18:00:00 CST" level=info msg=hello`},
+	} {
+		if text := c.formatter.Format(msg); text != c.expected {
+			t.Fatalf("expect '%s', got '%s'", c.expected, text)
+		}
+	}
+
+	sl := &syslog{}
+	SyslogTime(TimeFormat{Location: time.UTC})(sl)
+	if text := sl.Format(msg); text != "2022-08-05T10:00:00.5Z [I] hello" {
+		t.Fatalf("unexpected syslog message '%s'", text)
+	}
+
+	msg.Timestamp = processStart.Add(1500 * time.Millisecond)
+	if text := (&LogfmtFormatter{Time: TimeFormat{Layout: TimeElapsed}}).Format(msg); text != "ts=1.500 level=info msg=hello" {
+		t.Fatalf("unexpected elapsed time '%s'", text)
+	}
+}
+
+type nilMarshaler struct {
+	data []byte
+}
+
+func (m *nilMarshaler) MarshalJSON() ([]byte, error) { return m.data, nil }
+
+type nilError struct {
+	message string
+}
+
+func (e *nilError) Error() string  { return e.message }
+func (e *nilError) String() string { return e.message }
+
+func TestJSONFormatterConflict(t *testing.T) {
+	var (
+		marshaler *nilMarshaler
+		err       *nilError
+	)
+
+	msg := &Message{
+		Level:   LevelInfo,
+		Message: "hello",
+		Fields:  Fields{"level": 1, "fields.level": 2, "marshaler": marshaler, "error": err},
+	}
+
+	data := new(JSONFormatter).Format(msg)
+	if !strings.Contains(data, `"fields.level":2,"fields.fields.level":1`) {
+		t.Fatalf("unexpected conflict keys: %s", data)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal([]byte(data), &decoded); err != nil {
+		t.Fatalf("invalid json %s: %v", data, err)
+	}
+
+	if v, ok := decoded["marshaler"]; !ok || v != nil || decoded["error"] != nil {
+		t.Fatalf("expect nil pointers written as null: %s", data)
+	}
+}
+
+func TestLogfmtNilValues(t *testing.T) {
+	var err *nilError
+	msg := &Message{
+		Level:     LevelInfo,
+		Message:   "hello",
+		Timestamp: time.Date(2022, 8, 5, 10, 0, 0, 0, time.UTC),
+		Fields:    Fields{"error": err, "level": 1, "fields.level": 2},
+	}
+
+	expected := "ts=2022-08-05T10:00:00Z level=info msg=hello error=<nil> fields.level=2 fields.fields.level=1"
+	if text := new(LogfmtFormatter).Format(msg); text != expected {
+		t.Fatalf("expect '%s', got '%s'", expected, text)
+	}
+
+	tf, _ := NewTemplateFormatter("{field:error} {fields}")
+	if text := tf.Format(msg); text != "<nil> error=<nil> fields.level=2 level=1" {
+		t.Fatalf("unexpected text: '%s'", text)
+	}
+}
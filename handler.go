@@ -0,0 +1,204 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+// Formatter names used by admin handler
+const (
+	FormatterText   = "text"
+	FormatterJSON   = "json"
+	FormatterLogfmt = "logfmt"
+
+	// FormatterTemplate is only reported, since template formatter requires a layout
+	FormatterTemplate = "template"
+)
+
+type loggerState struct {
+	Name      string `json:"name"`
+	Level     string `json:"level,omitempty"`
+	Formatter string `json:"formatter,omitempty"`
+}
+
+type engineState struct {
+	Mode    string        `json:"mode,omitempty"`
+	Level   string        `json:"level,omitempty"`
+	Loggers []loggerState `json:"loggers,omitempty"`
+}
+
+// handler inspects and changes log engine's configuration over HTTP
+type handler struct {
+	engine *Engine
+}
+
+// Handler returns a http.Handler of engine, GET request lists engine's mode, global
+// level and registered loggers, PUT/POST request accepts a JSON body in the same
+// form to change them, fields absent from the body are left untouched, e.g.
+//
+//	{"mode": "debug", "loggers": [{"name": "console", "level": "Debug", "formatter": "json"}]}
+func (e *Engine) Handler() http.Handler {
+	return &handler{engine: e}
+}
+
+// Handler returns a http.Handler of log engine
+func Handler() http.Handler {
+	return logctx.Handler()
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodPut, http.MethodPost:
+		var state engineState
+		if err := json.NewDecoder(r.Body).Decode(&state); err != nil {
+			http.Error(w, fmt.Sprintf("invalid request body, %v", err), http.StatusBadRequest)
+			return
+		}
+
+		if status, err := h.apply(&state); err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.state())
+}
+
+func (h *handler) state() *engineState {
+	loggers := h.engine.snapshot()
+	state := &engineState{
+		Mode:    h.engine.Mode(),
+		Level:   h.engine.GlobalLevel().String(),
+		Loggers: make([]loggerState, 0, len(loggers)),
+	}
+
+	for name, logger := range loggers {
+		state.Loggers = append(state.Loggers, loggerState{
+			Name:      name,
+			Level:     logger.Level().String(),
+			Formatter: formatterName(formatterOf(logger)),
+		})
+	}
+
+	sort.Slice(state.Loggers, func(i, j int) bool {
+		return state.Loggers[i].Name < state.Loggers[j].Name
+	})
+
+	return state
+}
+
+// apply validates all changes before applying any of them, returns http status
+// code and error if state is invalid
+func (h *handler) apply(state *engineState) (int, error) {
+	var (
+		level      Level
+		levels     = make([]Level, len(state.Loggers))
+		formatters = make([]Formatter, len(state.Loggers))
+		loggers    = h.engine.snapshot()
+		err        error
+	)
+
+	if state.Mode != "" && state.Mode != ModeRelease && state.Mode != ModeDebug {
+		return http.StatusBadRequest, fmt.Errorf("invalid mode '%s', only accept release/debug", state.Mode)
+	}
+
+	if state.Level != "" {
+		if level, err = ParseLevel(state.Level); err != nil {
+			return http.StatusBadRequest, err
+		}
+	}
+
+	for i, ls := range state.Loggers {
+		logger, ok := loggers[ls.Name]
+		if !ok {
+			return http.StatusNotFound, fmt.Errorf("logger '%s' is not registered", ls.Name)
+		}
+
+		if ls.Level != "" {
+			if _, ok = logger.(LevelSetter); !ok {
+				return http.StatusBadRequest, fmt.Errorf("logger '%s' does not support changing level", ls.Name)
+			}
+
+			if levels[i], err = ParseLevel(ls.Level); err != nil {
+				return http.StatusBadRequest, err
+			}
+		}
+
+		if ls.Formatter != "" {
+			if formatters[i] = newFormatter(ls.Formatter); formatters[i] == nil {
+				return http.StatusBadRequest, fmt.Errorf("invalid formatter '%s', only accept text/json/logfmt", ls.Formatter)
+			}
+		}
+	}
+
+	if state.Mode != "" {
+		h.engine.SetMode(state.Mode)
+	}
+
+	if state.Level != "" {
+		h.engine.SetGlobalLevel(level)
+	}
+
+	for i, ls := range state.Loggers {
+		if ls.Level != "" {
+			h.engine.SetLevel(ls.Name, levels[i])
+		}
+
+		if formatters[i] != nil {
+			h.engine.SetFormatter(ls.Name, formatters[i])
+		}
+	}
+
+	return http.StatusOK, nil
+}
+
+func formatterOf(logger Logger) Formatter {
+	switch lg := logger.(type) {
+	case *console:
+		return lg.formatter.Load()
+	case *file:
+		return lg.formatter.Load()
+	case *syslog:
+		return lg.formatter.Load()
+	}
+
+	return nil
+}
+
+func formatterName(formatter Formatter) string {
+	switch formatter.(type) {
+	case nil:
+		return ""
+	case *TextFormatter:
+		return FormatterText
+	case *JSONFormatter:
+		return FormatterJSON
+	case *LogfmtFormatter:
+		return FormatterLogfmt
+	case *TemplateFormatter:
+		return FormatterTemplate
+	default:
+		return fmt.Sprintf("%T", formatter)
+	}
+}
+
+func newFormatter(name string) Formatter {
+	switch name {
+	case FormatterText:
+		return new(TextFormatter)
+	case FormatterJSON:
+		return new(JSONFormatter)
+	case FormatterLogfmt:
+		return new(LogfmtFormatter)
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package log
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	e := New(NewConsoleLogger(LevelInfo), NewConsoleLogger(LevelError, Name("stdout")))
+	server := httptest.NewServer(e.Handler())
+	defer server.Close()
+
+	request := func(method, body string) (int, *engineState) {
+		req, _ := http.NewRequest(method, server.URL, strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return resp.StatusCode, nil
+		}
+
+		state := new(engineState)
+		if err = json.NewDecoder(resp.Body).Decode(state); err != nil {
+			t.Fatal(err)
+		}
+
+		return resp.StatusCode, state
+	}
+
+	_, state := request(http.MethodGet, "")
+	if state.Mode != ModeDebug || len(state.Loggers) != 2 {
+		t.Fatalf("unexpected state: %+v", state)
+	}
+
+	if state.Loggers[0] != (loggerState{Name: Console, Level: "Info", Formatter: FormatterText}) {
+		t.Fatalf("unexpected logger state: %+v", state.Loggers[0])
+	}
+
+	_, state = request(http.MethodPut, `{"mode":"release","level":"W","loggers":[{"name":"stdout","level":"debug","formatter":"json"}]}`)
+	if state.Mode != ModeRelease || state.Level != "Warn" {
+		t.Fatalf("unexpected state: %+v", state)
+	}
+
+	if state.Loggers[1] != (loggerState{Name: "stdout", Level: "Debug", Formatter: FormatterJSON}) {
+		t.Fatalf("unexpected logger state: %+v", state.Loggers[1])
+	}
+
+	if !e.accepts("stdout", e.snapshot()["stdout"], LevelDebug) {
+		t.Fatalf("debug level set by handler should take effect in release mode")
+	}
+
+	for body, status := range map[string]int{
+		`{"mode":"unknown"}`:                                               http.StatusBadRequest,
+		`{"level":"unknown"}`:                                              http.StatusBadRequest,
+		`{"loggers":[{"name":"unknown","level":"debug"}]}`:                 http.StatusNotFound,
+		`{"loggers":[{"name":"stdout","formatter":"xml"}]}`:                http.StatusBadRequest,
+		`{"mode":"debug","loggers":[{"name":"stdout","level":"unknown"}]}`: http.StatusBadRequest,
+	} {
+		if code, _ := request(http.MethodPost, body); code != status {
+			t.Fatalf("%s: expect status %d, got %d", body, status, code)
+		}
+	}
+
+	if e.Mode() != ModeRelease {
+		t.Fatalf("invalid request should not change engine")
+	}
+
+	if code, _ := request(http.MethodDelete, ""); code != http.StatusMethodNotAllowed {
+		t.Fatalf("expect status %d, got %d", http.StatusMethodNotAllowed, code)
+	}
+}
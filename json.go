@@ -0,0 +1,161 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+const hex = "0123456789abcdef"
+
+func writeJSONKey(buf *bytes.Buffer, key string) {
+	writeJSONString(buf, key)
+	buf.WriteByte(':')
+}
+
+// writeJSONString writes s as a quoted JSON string, invalid UTF-8 bytes are replaced
+// by U+FFFD as encoding/json does
+func writeJSONString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if b >= 0x20 && b != '"' && b != '\\' {
+				i++
+				continue
+			}
+
+			buf.WriteString(s[start:i])
+			switch b {
+			case '"', '\\':
+				buf.WriteByte('\\')
+				buf.WriteByte(b)
+			case '\n':
+				buf.WriteString(`\n`)
+			case '\r':
+				buf.WriteString(`\r`)
+			case '\t':
+				buf.WriteString(`\t`)
+			default:
+				buf.WriteString(`\u00`)
+				buf.WriteByte(hex[b>>4])
+				buf.WriteByte(hex[b&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf.WriteString(s[start:i])
+			buf.WriteString(`\ufffd`)
+			i += size
+			start = i
+			continue
+		}
+
+		// U+2028 and U+2029 are escaped for JavaScript as encoding/json does
+		if r == '\u2028' || r == '\u2029' {
+			buf.WriteString(s[start:i])
+			buf.WriteString(`\u202`)
+			buf.WriteByte(hex[r&0xf])
+			i += size
+			start = i
+			continue
+		}
+
+		i += size
+	}
+
+	buf.WriteString(s[start:])
+	buf.WriteByte('"')
+}
+
+func writeJSONFloat(buf *bytes.Buffer, f float64, bits int) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		// not supported by JSON, written as string instead
+		writeJSONString(buf, strconv.FormatFloat(f, 'g', -1, bits))
+		return
+	}
+
+	var num [32]byte
+	buf.Write(strconv.AppendFloat(num[:0], f, 'g', -1, bits))
+}
+
+// writeJSONValue writes value in JSON, common types are written without reflection,
+// errors are written by their messages, nil pointers are written as null, and others
+// are marshaled by encoding/json
+func writeJSONValue(buf *bytes.Buffer, value interface{}) {
+	var num [64]byte
+
+	switch v := value.(type) {
+	case nil:
+		buf.WriteString("null")
+	case string:
+		writeJSONString(buf, v)
+	case bool:
+		buf.Write(strconv.AppendBool(num[:0], v))
+	case int:
+		buf.Write(strconv.AppendInt(num[:0], int64(v), 10))
+	case int8:
+		buf.Write(strconv.AppendInt(num[:0], int64(v), 10))
+	case int16:
+		buf.Write(strconv.AppendInt(num[:0], int64(v), 10))
+	case int32:
+		buf.Write(strconv.AppendInt(num[:0], int64(v), 10))
+	case int64:
+		buf.Write(strconv.AppendInt(num[:0], v, 10))
+	case uint:
+		buf.Write(strconv.AppendUint(num[:0], uint64(v), 10))
+	case uint8:
+		buf.Write(strconv.AppendUint(num[:0], uint64(v), 10))
+	case uint16:
+		buf.Write(strconv.AppendUint(num[:0], uint64(v), 10))
+	case uint32:
+		buf.Write(strconv.AppendUint(num[:0], uint64(v), 10))
+	case uint64:
+		buf.Write(strconv.AppendUint(num[:0], v, 10))
+	case float32:
+		writeJSONFloat(buf, float64(v), 32)
+	case float64:
+		writeJSONFloat(buf, v, 64)
+	case time.Time:
+		buf.WriteByte('"')
+		buf.Write(v.AppendFormat(num[:0], time.RFC3339Nano))
+		buf.WriteByte('"')
+	case Level:
+		writeJSONString(buf, v.String())
+	case json.Marshaler:
+		if isNil(v) {
+			// written by encoding/json as null too
+			buf.WriteString("null")
+		} else {
+			writeJSONMarshal(buf, v)
+		}
+	case error:
+		if isNil(v) {
+			buf.WriteString("null")
+		} else {
+			writeJSONString(buf, v.Error())
+		}
+	default:
+		writeJSONMarshal(buf, v)
+	}
+}
+
+// writeJSONMarshal writes value marshaled by encoding/json, and the error message as
+// a string if it fails
+func writeJSONMarshal(buf *bytes.Buffer, value interface{}) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		writeJSONString(buf, "!ERROR: "+err.Error())
+		return
+	}
+
+	buf.Write(data)
+}
@@ -3,11 +3,11 @@ package log
 import (
 	"fmt"
 	"os"
-	"path/filepath"
 	"regexp"
 	"runtime"
 	"runtime/debug"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 )
@@ -72,6 +72,23 @@ func (l Level) Tag() string {
 	}
 }
 
+// ParseLevel parses level from level name or tag, case insensitive, e.g. "Info",
+// "info", "I" and "[I]" are all parsed to LevelInfo
+func ParseLevel(s string) (Level, error) {
+	name := strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(s), "["), "]"))
+	for l := LevelPanic; l <= LevelTrace; l++ {
+		if name == strings.ToLower(l.String()) || name == strings.ToLower(l.Tag()[1:2]) {
+			return l, nil
+		}
+	}
+
+	if name == "warning" {
+		return LevelWarn, nil
+	}
+
+	return LevelPanic, fmt.Errorf("invalid log level '%s'", s)
+}
+
 func (l Level) MarshalJSON() ([]byte, error) {
 	str := l.String()
 	data := make([]byte, 0, len(str)+2)
@@ -86,6 +103,7 @@ const (
 	ModeDebug   = "debug"
 )
 
+// Default logger names
 const (
 	Console = "console"
 	File    = "file"
@@ -99,17 +117,11 @@ const (
 )
 
 var (
-	logctx *context = nil
+	logctx *Engine = nil
 )
 
 func init() {
-	logctx = &context{
-		mode:        "debug",
-		loggers:     make(map[string]Logger),
-		initialized: 1,
-	}
-
-	logctx.regist(NewConsoleLogger(LevelDebug, Colorful()))
+	logctx = New(NewConsoleLogger(LevelDebug, Colorful()))
 }
 
 // Message represents Log message record, minimal log dispatch unit
@@ -120,11 +132,32 @@ type Message struct {
 	Line      int       `json:"line,omitempty"`
 	Message   string    `json:"message"`
 	Timestamp time.Time `json:"timestamp"`
+	Stack     string    `json:"stacktrace,omitempty"`
 	Fields    Fields    `json:"-"`
+	keys      []string  // insertion order of fields if known
 }
 
 type Option func(Logger)
 
+// Name sets logger's name, which is used to regist and address the logger in log
+// engine, so that loggers of the same kind can be registered side by side.
+func Name(name string) Option {
+	return func(l Logger) {
+		if name == "" {
+			return
+		}
+
+		switch lg := l.(type) {
+		case *console:
+			lg.name = name
+		case *file:
+			lg.name = name
+		case *syslog:
+			lg.name = name
+		}
+	}
+}
+
 type Formatter interface {
 	Format(*Message) string
 }
@@ -141,76 +174,275 @@ type Logger interface {
 	Close() error
 }
 
-type context struct {
-	mode        string
-	initialized uint32
-	loggers     map[string]Logger
+// LevelSetter is implemented by loggers whose level can be changed while logging
+type LevelSetter interface {
+	SetLevel(level Level)
 }
 
-// internal log function
-func (ctx *context) log(level Level, message string, fields ...Fields) {
-	if len(message) == 0 {
-		return
+// Flusher is implemented by loggers write messages asynchronously
+type Flusher interface {
+	// Flush writes all pending messages
+	Flush() error
+}
+
+// Engine dispatches log messages to its registered loggers. The package level
+// functions operate on a default engine, which prints to console, use New to
+// create an engine with its own loggers, mode and formatters.
+type Engine struct {
+	mode         atomic.Value // string
+	level        uint32       // global level, messages above it are dropped
+	callerLevels uint32       // bit mask of levels logged with caller
+	callerPath   uint32       // CallerPath
+	stackLevel   uint32       // messages below it are logged with stack trace, 0 means disabled
+	initialized  uint32
+	mu           sync.Mutex   // serializes updates of loggers and extractors
+	loggers      atomic.Value // map[string]Logger, replaced on update and never modified
+	extractors   atomic.Value // []ContextExtractor, replaced on update and never modified
+	leveled      atomic.Value // map[string]struct{}, loggers whose level is set by SetLevel
+}
+
+// New creates a log engine in debug mode with the given loggers registered
+func New(loggers ...Logger) *Engine {
+	e := &Engine{
+		level:        uint32(LevelTrace),
+		callerLevels: defaultCallerLevels,
+		stackLevel:   uint32(LevelPanic) + 1,
+		initialized:  1,
 	}
 
-	if ctx.mode == ModeRelease {
-		skip := true
-		for _, logger := range ctx.loggers {
-			if logger.Level() >= level && level < LevelDebug {
-				// logger found
-				skip = false
-				break
-			}
-		}
+	e.mode.Store(ModeDebug)
+	e.loggers.Store(make(map[string]Logger))
+	e.leveled.Store(make(map[string]struct{}))
 
-		if skip {
-			return
-		}
+	for _, logger := range loggers {
+		e.Regist(logger)
+	}
+
+	return e
+}
+
+// Default returns the engine used by the package level functions
+func Default() *Engine {
+	return logctx
+}
+
+// internal log function, keys is the order of fields, which can be nil
+func (e *Engine) log(level Level, message string, fields Fields, keys []string) {
+	e.output(0, level, message, fields, keys)
+	e.terminate(level, message)
+}
+
+// output logs message, caller is found by skipping output, the internal log function
+// of engine or entry, and the function called by user, plus extra skip stack frames
+func (e *Engine) output(skip int, level Level, message string, fields Fields, keys []string) {
+	if len(message) == 0 || !e.enabled(level) {
+		return
 	}
 
 	msg := &Message{
 		Level:     level,
 		Message:   message,
 		Timestamp: time.Now(),
+		Fields:    fields,
+		keys:      keys,
+	}
+
+	if e.withCaller(level) {
+		var pcs [1]uintptr
+		if runtime.Callers(4+skip, pcs[:]) > 0 {
+			e.setCaller(msg, pcs[0])
+		}
+	}
+
+	e.outputMessage(msg, 2+skip)
+}
+
+// outputMessage adds stack trace to msg and dispatches it, stack trace of caller
+// starts at the caller of the function calling outputMessage plus extra skip stack
+// frames
+func (e *Engine) outputMessage(msg *Message, skip int) {
+	if atomic.LoadUint32(&e.stackLevel) != 0 {
+		// stack trace carried by error is logged at any level
+		if msg.Stack = errorStack(msg); msg.Stack == "" && e.withStacktrace(msg.Level) {
+			msg.Stack = stacktrace(2 + skip)
+		}
+	}
+
+	e.dispatch(msg)
+}
+
+// enabled reports whether any logger will write a message of level
+func (e *Engine) enabled(level Level) bool {
+	if level > e.GlobalLevel() {
+		return false
 	}
 
-	if len(fields) > 0 {
-		msg.Fields = fields[0]
+	if e.Mode() == ModeDebug {
+		return true
 	}
 
-	if level == LevelTrace {
-		pc, file, line, ok := runtime.Caller(2)
-		if ok {
-			msg.Filename = filepath.Base(file)
-			msg.Line = line
-			msg.Function = runtime.FuncForPC(pc).Name()
+	for name, logger := range e.snapshot() {
+		if e.accepts(name, logger, level) {
+			// logger found
+			return true
 		}
 	}
 
-	for _, logger := range ctx.loggers {
-		if ctx.mode == ModeDebug || (logger.Level() >= level && level < LevelDebug) {
+	return false
+}
+
+// accepts reports whether logger accepts messages of level in release mode, debug
+// and trace messages are only accepted by loggers whose level is set by SetLevel
+func (e *Engine) accepts(name string, logger Logger, level Level) bool {
+	if logger.Level() < level {
+		return false
+	}
+
+	if level < LevelDebug {
+		return true
+	}
+
+	_, ok := e.leveled.Load().(map[string]struct{})[name]
+	return ok
+}
+
+// dispatch writes msg to loggers accept its level
+func (e *Engine) dispatch(msg *Message) {
+	mode := e.Mode()
+	for name, logger := range e.snapshot() {
+		if mode == ModeDebug || e.accepts(name, logger, msg.Level) {
 			logger.Write(msg)
 		}
 	}
 }
 
-func (ctx *context) regist(logger Logger) {
+// setLeveled marks the named logger as its level is set by SetLevel or not, e.mu
+// MUST be held
+func (e *Engine) setLeveled(name string, leveled bool) {
+	current := e.leveled.Load().(map[string]struct{})
+	if _, ok := current[name]; ok == leveled {
+		return
+	}
+
+	names := make(map[string]struct{}, len(current)+1)
+	for n := range current {
+		names[n] = struct{}{}
+	}
+
+	if leveled {
+		names[name] = struct{}{}
+	} else {
+		delete(names, name)
+	}
+
+	e.leveled.Store(names)
+}
+
+// snapshot returns current loggers, the returned map MUST NOT be modified
+func (e *Engine) snapshot() map[string]Logger {
+	return e.loggers.Load().(map[string]Logger)
+}
+
+// update replaces loggers with a modified copy, and returns the logger removed or
+// replaced by fn
+func (e *Engine) update(fn func(loggers map[string]Logger) Logger) Logger {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	current := e.snapshot()
+	loggers := make(map[string]Logger, len(current)+1)
+	for name, logger := range current {
+		loggers[name] = logger
+	}
+
+	old := fn(loggers)
+	e.loggers.Store(loggers)
+
+	return old
+}
+
+// Regist adds a logger to engine, a registered logger with the same name will be
+// closed and replaced. It's safe to regist loggers while logging.
+func (e *Engine) Regist(logger Logger) {
 	if logger == nil {
 		return
 	}
 
-	if l, ok := ctx.loggers[logger.Name()]; ok {
-		l.Close()
+	old := e.update(func(loggers map[string]Logger) Logger {
+		old := loggers[logger.Name()]
+		loggers[logger.Name()] = logger
+		e.setLeveled(logger.Name(), false)
+		return old
+	})
+
+	if old != nil {
+		old.Close()
+	}
+}
+
+// Unregist removes the named logger from engine and closes it
+func (e *Engine) Unregist(name string) error {
+	old := e.update(func(loggers map[string]Logger) Logger {
+		old := loggers[name]
+		delete(loggers, name)
+		e.setLeveled(name, false)
+		return old
+	})
+
+	if old == nil {
+		return fmt.Errorf("logger '%s' is not registered", name)
+	}
+
+	return old.Close()
+}
+
+// SetLevel sets level of the named logger, it's safe to change level while logging.
+// In release mode, debug and trace messages are only written to loggers whose level
+// is set by SetLevel, e.g. SetLevel(Console, LevelDebug) enables debug messages of
+// console logger temporarily.
+func (e *Engine) SetLevel(logger string, level Level) error {
+	// looks up logger with lock held, so a logger unregistered or replaced
+	// concurrently is neither changed nor marked as leveled
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	l, ok := e.snapshot()[logger]
+	if !ok {
+		return fmt.Errorf("logger '%s' is not registered", logger)
+	}
+
+	ls, ok := l.(LevelSetter)
+	if !ok {
+		return fmt.Errorf("logger '%s' does not support changing level", logger)
 	}
 
-	ctx.loggers[logger.Name()] = logger
+	ls.SetLevel(level)
+	e.setLeveled(logger, true)
+	return nil
+}
+
+// SetGlobalLevel sets engine's global level, messages above it are dropped before
+// dispatching to any logger, whatever the mode and loggers' levels are.
+func (e *Engine) SetGlobalLevel(level Level) {
+	atomic.StoreUint32(&e.level, uint32(level))
+}
+
+// GlobalLevel returns engine's global level
+func (e *Engine) GlobalLevel() Level {
+	return Level(atomic.LoadUint32(&e.level))
+}
+
+// Mode returns engine mode, release or debug
+func (e *Engine) Mode() string {
+	return e.mode.Load().(string)
 }
 
-func (ctx *context) setMode(mode string) {
+// SetMode sets engine mode, only accept release/debug/dev/devel
+func (e *Engine) SetMode(mode string) {
 	if mode == ModeDebug || mode == "dev" || mode == "devel" {
-		ctx.mode = ModeDebug
+		e.mode.Store(ModeDebug)
 	} else if mode == ModeRelease {
-		ctx.mode = ModeRelease
+		e.mode.Store(ModeRelease)
 	} else {
 		fmt.Fprintf(os.Stderr, "invalid logger mode, only accept release/debug/dev/devel")
 		debug.PrintStack()
@@ -218,15 +450,16 @@ func (ctx *context) setMode(mode string) {
 	}
 }
 
-func (ctx *context) setFormatter(logger string, formatter Formatter) error {
-	if l, ok := ctx.loggers[logger]; ok {
+// SetFormatter sets formatter of the named logger
+func (e *Engine) SetFormatter(logger string, formatter Formatter) error {
+	if l, ok := e.snapshot()[logger]; ok {
 		switch lg := l.(type) {
 		case *console:
-			lg.formatter = formatter
+			lg.formatter.Store(formatter)
 		case *file:
-			lg.formatter = formatter
+			lg.formatter.Store(formatter)
 		case *syslog:
-			lg.formatter = formatter
+			lg.formatter.Store(formatter)
 		}
 
 		return nil
@@ -235,79 +468,166 @@ func (ctx *context) setFormatter(logger string, formatter Formatter) error {
 	return fmt.Errorf("logger '%s' is not supported", logger)
 }
 
-func (ctx *context) close() {
-	for key, logger := range ctx.loggers {
-		logger.Close()
-		delete(ctx.loggers, key)
+// Sync flushes all registered loggers implement Flusher, and returns errors of them
+func (e *Engine) Sync() error {
+	var errs multiError
+	for name, logger := range e.snapshot() {
+		if f, ok := logger.(Flusher); ok {
+			if err := f.Flush(); err != nil {
+				errs = append(errs, fmt.Errorf("logger '%s': %w", name, err))
+			}
+		}
+	}
+
+	return errs.err()
+}
+
+// Close closes all registered loggers of engine, and returns errors of them
+func (e *Engine) Close() error {
+	e.mu.Lock()
+	loggers := e.snapshot()
+	e.loggers.Store(make(map[string]Logger))
+	e.leveled.Store(make(map[string]struct{}))
+	e.mu.Unlock()
+
+	var errs multiError
+	for name, logger := range loggers {
+		if err := logger.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("logger '%s': %w", name, err))
+		}
 	}
 
-	atomic.StoreUint32(&ctx.initialized, 0)
+	atomic.StoreUint32(&e.initialized, 0)
+
+	return errs.err()
+}
+
+// Trace print trace message, which prints more details
+func (e *Engine) Trace(args ...interface{}) {
+	e.log(LevelTrace, formatLogMessage(args...), nil, nil)
+}
+
+// Debug print debug message
+func (e *Engine) Debug(args ...interface{}) {
+	e.log(LevelDebug, formatLogMessage(args...), nil, nil)
+}
+
+func (e *Engine) Verbose(args ...interface{}) {
+	e.log(LevelVerbose, formatLogMessage(args...), nil, nil)
+}
+
+// Info print information message
+func (e *Engine) Info(args ...interface{}) {
+	e.log(LevelInfo, formatLogMessage(args...), nil, nil)
+}
+
+// Warning print warning message
+func (e *Engine) Warning(args ...interface{}) {
+	e.log(LevelWarn, formatLogMessage(args...), nil, nil)
+}
+
+// Error print error message
+func (e *Engine) Error(args ...interface{}) {
+	e.log(LevelError, formatLogMessage(args...), nil, nil)
+}
+
+// Fatal print fatal error message, then app quits after exit handlers run and
+// loggers are flushed
+func (e *Engine) Fatal(args ...interface{}) {
+	e.log(LevelFatal, formatLogMessage(args...), nil, nil)
+}
+
+// Panic print panic message, then panics with the message after loggers are flushed
+func (e *Engine) Panic(args ...interface{}) {
+	e.log(LevelPanic, formatLogMessage(args...), nil, nil)
 }
 
 // Regist adds a logger, Log package default add one logger(console), means default all
 // the log message will print to console. But you can use this function to add new
 // logger to log engine.
 func Regist(logger Logger) {
-	logctx.regist(logger)
+	logctx.Regist(logger)
+}
+
+// Sync flushes all loggers of log engine
+func Sync() error {
+	return logctx.Sync()
 }
 
 // Close closes log engine
-func Close() {
-	logctx.close()
+func Close() error {
+	return logctx.Close()
+}
+
+// Unregist removes the named logger from log engine and closes it
+func Unregist(name string) error {
+	return logctx.Unregist(name)
 }
 
 func SetMode(mode string) {
-	logctx.setMode(mode)
+	logctx.SetMode(mode)
 }
 
 func SetFormatter(logger string, formatter Formatter) error {
-	return logctx.setFormatter(logger, formatter)
+	return logctx.SetFormatter(logger, formatter)
+}
+
+// SetLevel sets level of the named logger
+func SetLevel(logger string, level Level) error {
+	return logctx.SetLevel(logger, level)
+}
+
+// SetGlobalLevel sets global level of log engine
+func SetGlobalLevel(level Level) {
+	logctx.SetGlobalLevel(level)
 }
 
 // Trace print trace message, which prints more details
 func Trace(args ...interface{}) {
-	logctx.log(LevelTrace, formatLogMessage(args...))
+	logctx.log(LevelTrace, formatLogMessage(args...), nil, nil)
 }
 
 // Debug print debug message
 func Debug(args ...interface{}) {
-	logctx.log(LevelDebug, formatLogMessage(args...))
+	logctx.log(LevelDebug, formatLogMessage(args...), nil, nil)
 }
 
 func Verbose(args ...interface{}) {
-	logctx.log(LevelVerbose, formatLogMessage(args...))
+	logctx.log(LevelVerbose, formatLogMessage(args...), nil, nil)
 }
 
 // Info print information message
 func Info(args ...interface{}) {
-	logctx.log(LevelInfo, formatLogMessage(args...))
+	logctx.log(LevelInfo, formatLogMessage(args...), nil, nil)
 }
 
 // Warning print warning message
 func Warning(args ...interface{}) {
-	logctx.log(LevelWarn, formatLogMessage(args...))
+	logctx.log(LevelWarn, formatLogMessage(args...), nil, nil)
 }
 
 // Error print error message
 func Error(args ...interface{}) {
-	logctx.log(LevelError, formatLogMessage(args...))
+	logctx.log(LevelError, formatLogMessage(args...), nil, nil)
 }
 
-// Fatal print fatal error message, and app will quit if this function called
+// Fatal print fatal error message, then app quits after exit handlers run and
+// loggers are flushed
 func Fatal(args ...interface{}) {
-	logctx.log(LevelFatal, formatLogMessage(args...))
-	os.Exit(1)
+	logctx.log(LevelFatal, formatLogMessage(args...), nil, nil)
 }
 
-// Panic print panic message, and app will trigger panic message if called
+// Panic print panic message, then panics with the message after loggers are flushed
 func Panic(args ...interface{}) {
-	logctx.log(LevelPanic, formatLogMessage(args...))
-	debug.PrintStack()
-	os.Exit(1)
+	logctx.log(LevelPanic, formatLogMessage(args...), nil, nil)
 }
 
 var fmtsign = regexp.MustCompile(`%[\+\-\#\s\d.]{0,}[vtTbcdoOqxXUeEfFgGsp]`)
 
+// formatLogMessage formats arguments of Info, Error, etc. If the first argument is a
+// string with format verbs, it's used as format of the rest arguments, e.g. ("count
+// %d", 1) is "count 1", otherwise arguments are joined by spaces, e.g. ("count", 1)
+// is "count 1".
 func formatLogMessage(args ...interface{}) string {
 	if len(args) == 0 {
 		return ""
@@ -322,7 +642,7 @@ func formatLogMessage(args ...interface{}) string {
 			return msg
 		}
 
-		if fmtsign.MatchString(msg) {
+		if !fmtsign.MatchString(msg) {
 			msg += strings.Repeat(" %v", len(args)-1)
 		}
 	default:
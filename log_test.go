@@ -1,11 +1,22 @@
 package log
 
 import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
 	"fmt"
+	"io"
 	"io/fs"
+	stdlog "log"
+	"os"
+	"os/exec"
 	"path/filepath"
 	"regexp"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
 )
 
 func TestLog(t *testing.T) {
@@ -23,6 +34,12 @@ func TestLog(t *testing.T) {
 	Trace("Test Trace")
 	Error("Test Error")
 	// Fatal("Test Fatal")
+
+	defer func() {
+		if r := recover(); r != "Test Panic" {
+			t.Fatalf("expect panic with message, got %v", r)
+		}
+	}()
 	Panic("Test Panic")
 }
 
@@ -94,3 +111,889 @@ func TestFmtSign(t *testing.T) {
 		}
 	}
 }
+
+type memoryLogger struct {
+	name     string
+	level    Level
+	messages []*Message
+}
+
+func (m *memoryLogger) Name() string       { return m.name }
+func (m *memoryLogger) Level() Level       { return m.level }
+func (m *memoryLogger) Write(msg *Message) { m.messages = append(m.messages, msg) }
+func (m *memoryLogger) Close() error       { return nil }
+
+func TestEngine(t *testing.T) {
+	ml := &memoryLogger{name: "memory", level: LevelInfo}
+	e := New(ml)
+	e.SetMode(ModeRelease)
+
+	e.Debug("debug")
+	e.Info("info")
+	e.Error("error")
+
+	if len(ml.messages) != 2 {
+		t.Fatalf("expect 2 messages, got %d", len(ml.messages))
+	}
+
+	if ml.messages[0].Message != "info" || ml.messages[1].Level != LevelError {
+		t.Fatalf("unexpected messages: %+v, %+v", ml.messages[0], ml.messages[1])
+	}
+
+	e.Close()
+	e.Info("closed")
+	if len(ml.messages) != 2 {
+		t.Fatalf("closed engine should not dispatch messages")
+	}
+}
+
+func TestEngineConcurrentRegist(t *testing.T) {
+	e := New()
+	e.SetMode(ModeRelease)
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					e.Error("concurrent")
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 100; i++ {
+		e.Regist(NewConsoleLogger(LevelPanic))
+		e.SetFormatter(Console, new(JSONFormatter))
+		e.Unregist(Console)
+	}
+
+	close(done)
+	wg.Wait()
+
+	if err := e.Unregist(Console); err == nil {
+		t.Fatalf("expect error when unregist an unknown logger")
+	}
+}
+
+func TestNamedFileLoggers(t *testing.T) {
+	dir := t.TempDir()
+	e := New(
+		NewFileLogger(LevelInfo, Name("access"), Path(dir)),
+		NewFileLogger(LevelError, Name("error"), Path(dir)),
+	)
+	e.SetMode(ModeRelease)
+
+	if err := e.SetFormatter("access", new(JSONFormatter)); err != nil {
+		t.Fatal(err)
+	}
+
+	e.Info("info")
+	e.Error("error")
+	e.Close()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("expect 2 log files, got %d", len(entries))
+	}
+
+	for _, entry := range entries {
+		data, _ := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if strings.Contains(entry.Name(), "-access-") && strings.Count(string(data), "\n") != 2 {
+			t.Fatalf("expect 2 lines in access log, got %q", data)
+		}
+
+		if strings.Contains(entry.Name(), "-error-") && strings.Count(string(data), "\n") != 1 {
+			t.Fatalf("expect 1 line in error log, got %q", data)
+		}
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for l := LevelPanic; l <= LevelTrace; l++ {
+		for _, s := range []string{l.String(), strings.ToLower(l.String()), l.Tag(), l.Tag()[1:2]} {
+			level, err := ParseLevel(s)
+			if err != nil || level != l {
+				t.Fatalf("parse '%s' expect %v, got %v, %v", s, l, level, err)
+			}
+		}
+	}
+
+	if _, err := ParseLevel("unknown"); err == nil {
+		t.Fatalf("expect error when parse invalid level")
+	}
+}
+
+func TestSetLevel(t *testing.T) {
+	cl := NewConsoleLogger(LevelError)
+	e := New(cl)
+	e.SetMode(ModeRelease)
+
+	if err := e.SetLevel(Console, LevelInfo); err != nil {
+		t.Fatal(err)
+	}
+
+	if cl.Level() != LevelInfo {
+		t.Fatalf("expect level %v, got %v", LevelInfo, cl.Level())
+	}
+
+	if err := e.SetLevel("memory", LevelInfo); err == nil {
+		t.Fatalf("expect error when set level of an unknown logger")
+	}
+
+	ml := &memoryLogger{name: "memory", level: LevelInfo}
+	e.Regist(ml)
+	if err := e.SetLevel("memory", LevelDebug); err == nil {
+		t.Fatalf("expect error when logger does not support changing level")
+	}
+
+	e.SetGlobalLevel(LevelWarn)
+	e.Info("info")
+	e.Warning("warning")
+	if len(ml.messages) != 1 || ml.messages[0].Level != LevelWarn {
+		t.Fatalf("expect only warning message dispatched, got %d messages", len(ml.messages))
+	}
+}
+
+type levelLogger struct {
+	memoryLogger
+}
+
+func (l *levelLogger) SetLevel(level Level) { l.level = level }
+
+func TestSetDebugLevel(t *testing.T) {
+	ll := &levelLogger{memoryLogger{name: "memory", level: LevelDebug}}
+	e := New(ll)
+	e.SetMode(ModeRelease)
+
+	e.Debug("dropped in release mode")
+	if len(ll.messages) != 0 {
+		t.Fatalf("expect debug message dropped in release mode, got %d messages", len(ll.messages))
+	}
+
+	if err := e.SetLevel("memory", LevelDebug); err != nil {
+		t.Fatal(err)
+	}
+
+	e.Debug("debug")
+	e.Trace("trace")
+	if len(ll.messages) != 1 || ll.messages[0].Message != "debug" {
+		t.Fatalf("expect debug message written after SetLevel, got %d messages", len(ll.messages))
+	}
+
+	// regist a logger of the same name resets it
+	e.Regist(&levelLogger{memoryLogger{name: "memory", level: LevelDebug}})
+	if e.enabled(LevelDebug) {
+		t.Fatalf("expect debug messages disabled for the new logger")
+	}
+}
+
+func TestFormatLogMessage(t *testing.T) {
+	for _, c := range []struct {
+		args     []interface{}
+		expected string
+	}{
+		{[]interface{}{"count %d", 1}, "count 1"},
+		{[]interface{}{"count", 1, 2}, "count 1 2"},
+		{[]interface{}{"%s=%v", "a", 1}, "a=1"},
+		{[]interface{}{42, "answer"}, "42 answer"},
+		{[]interface{}{"literal %d"}, "literal %d"},
+	} {
+		if msg := formatLogMessage(c.args...); msg != c.expected {
+			t.Fatalf("%v: expect '%s', got '%s'", c.args, c.expected, msg)
+		}
+	}
+}
+
+func TestLogfw(t *testing.T) {
+	ml := &memoryLogger{name: "memory", level: LevelInfo}
+	e := New(ml)
+
+	e.Info("count %d", 1)
+	e.Info("count", 2)
+	e.Infof("%d%% done", 100)
+	e.Infof("%s", "literal %d")
+	e.Infow("request", "id", 1, 2, "two", "odd")
+
+	expected := []string{"count 1", "count 2", "100% done", "literal %d", "request"}
+	for i, msg := range ml.messages {
+		if msg.Message != expected[i] {
+			t.Fatalf("expect '%s', got '%s'", expected[i], msg.Message)
+		}
+	}
+
+	fields := ml.messages[4].Fields
+	if len(fields) != 3 || fields["id"] != 1 || fields["2"] != "two" || fields[badKey] != "odd" {
+		t.Fatalf("unexpected fields: %v", fields)
+	}
+}
+
+func TestEntry(t *testing.T) {
+	ml := &memoryLogger{name: "memory", level: LevelInfo}
+	e := New(ml)
+
+	fields := Fields{"request_id": "r1"}
+	parent := e.WithFields(fields)
+	child := parent.With("user_id", 42).WithError(fmt.Errorf("denied"))
+	fields["route"] = "/login"
+
+	parent.Info("parent")
+	child.Infow("child", "route", "/login")
+
+	if len(ml.messages[0].Fields) != 1 {
+		t.Fatalf("parent fields should not be modified, got %v", ml.messages[0].Fields)
+	}
+
+	if len(ml.messages[1].Fields) != 4 || ml.messages[1].Fields[ErrorKey].(error).Error() != "denied" {
+		t.Fatalf("unexpected child fields: %v", ml.messages[1].Fields)
+	}
+}
+
+func TestContext(t *testing.T) {
+	type traceKey struct{}
+
+	ml := &memoryLogger{name: "memory", level: LevelInfo}
+	e := New(ml)
+	e.AddContextExtractor(func(ctx context.Context) Fields {
+		if id, ok := ctx.Value(traceKey{}).(string); ok {
+			return Fields{"trace_id": id}
+		}
+		return nil
+	})
+
+	ctx := ContextWithFields(context.Background(), Fields{"request_id": "r1"})
+	ctx = context.WithValue(ctx, traceKey{}, "t1")
+
+	e.InfoContext(ctx, "context")
+	e.InfoContext(context.Background(), "background")
+
+	if fields := ml.messages[0].Fields; len(fields) != 2 || fields["request_id"] != "r1" || fields["trace_id"] != "t1" {
+		t.Fatalf("unexpected fields: %v", fields)
+	}
+
+	if len(ml.messages[1].Fields) != 0 {
+		t.Fatalf("unexpected fields: %v", ml.messages[1].Fields)
+	}
+
+	if FromContext(ctx).fields["request_id"] != "r1" {
+		t.Fatalf("entry is not carried by context")
+	}
+
+	// extractors are not run for messages not dispatched
+	extracted := 0
+	e.AddContextExtractor(func(ctx context.Context) Fields {
+		extracted++
+		return nil
+	})
+
+	e.SetMode(ModeRelease)
+	e.DebugContext(ctx, "debug")
+	e.TraceContext(ctx, "trace")
+	if extracted != 0 || len(ml.messages) != 2 {
+		t.Fatalf("expect no extraction for disabled messages, extracted %d times", extracted)
+	}
+
+	// messages are logged by engine of the entry carried by context
+	other := &memoryLogger{name: "other", level: LevelInfo}
+	oe := New(other)
+	oe.SetCallerLevels(LevelError, LevelTrace)
+	oe.AddContextExtractor(func(ctx context.Context) Fields {
+		return Fields{"engine": "other"}
+	})
+
+	ctx = NewContext(context.Background(), oe.With("key", "value").AddCallerSkip(1))
+	contextWrapper(e, ctx, "other")
+
+	if len(ml.messages) != 2 || len(other.messages) != 1 {
+		t.Fatalf("expect message logged by other engine, got %d and %d messages", len(ml.messages), len(other.messages))
+	}
+
+	if msg := other.messages[0]; msg.Fields["key"] != "value" || msg.Fields["engine"] != "other" || msg.Function != "github.com/derekhjray/glog.TestContext" {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+}
+
+func contextWrapper(e *Engine, ctx context.Context, msg string) {
+	e.ErrorContext(ctx, msg)
+}
+
+func TestStdLogger(t *testing.T) {
+	ml := &memoryLogger{name: "memory", level: LevelInfo}
+	e := New(ml)
+
+	e.NewStdLogger(LevelError).Print("std error")
+	w := e.Writer(LevelWarn)
+	fmt.Fprint(w, "first\r\nsec")
+	fmt.Fprint(w, "ond\n\nthi")
+	fmt.Fprint(w, "rd")
+	w.Close()
+
+	restore := e.RedirectStdLog(LevelInfo)
+	stdlog.Println("redirected")
+	restore()
+
+	expected := []string{"std error", "first", "second", "third", "redirected"}
+	if len(ml.messages) != len(expected) {
+		t.Fatalf("expect %d messages, got %d", len(expected), len(ml.messages))
+	}
+
+	for i, msg := range ml.messages {
+		if msg.Message != expected[i] {
+			t.Fatalf("expect '%s', got '%s'", expected[i], msg.Message)
+		}
+	}
+
+	if ml.messages[0].Level != LevelError || ml.messages[1].Level != LevelWarn {
+		t.Fatalf("unexpected levels: %v, %v", ml.messages[0].Level, ml.messages[1].Level)
+	}
+}
+
+func logWrapper(entry *Entry, msg string) {
+	entry.Error(msg)
+}
+
+func TestCaller(t *testing.T) {
+	ml := &memoryLogger{name: "memory", level: LevelInfo}
+	e := New(ml)
+	e.SetCallerLevels(LevelError, LevelTrace)
+
+	e.Info("info")
+	e.Error("engine")
+	e.With("key", "value").Error("entry")
+	logWrapper(e.AddCallerSkip(1), "wrapper")
+	e.NewStdLogger(LevelError).Print("stdlog")
+	e.Writer(LevelError).Write([]byte("writer\n"))
+	w := e.Writer(LevelError)
+	w.Write([]byte("partial"))
+	w.Close()
+
+	e.SetCallerPath(CallerRelative)
+	e.Error("relative")
+
+	if ml.messages[0].Filename != "" {
+		t.Fatalf("info message should not be logged with caller")
+	}
+
+	for _, msg := range ml.messages[1:] {
+		if !strings.HasSuffix(msg.Filename, "log_test.go") || msg.Function != "github.com/derekhjray/glog.TestCaller" {
+			t.Fatalf("unexpected caller of '%s': %s:%d %s", msg.Message, msg.Filename, msg.Line, msg.Function)
+		}
+	}
+
+	if ml.messages[7].Filename != "log_test.go" {
+		t.Fatalf("unexpected relative filename: %s", ml.messages[7].Filename)
+	}
+
+	if filename := relativePath("/src/glog/cmd/app/main.go", "github.com/derekhjray/glog/cmd/app.(*server).run"); filename != "cmd/app/main.go" {
+		t.Fatalf("unexpected relative filename: %s", filename)
+	}
+
+	if filename := relativePath("/src/glog/cmd/app.v2/main.go", "github.com/derekhjray/glog/cmd/app%2ev2.run"); filename != "cmd/app.v2/main.go" {
+		t.Fatalf("unexpected relative filename: %s", filename)
+	}
+
+	if filename := relativePath("/pkg/mod/gopkg.in/yaml.v3/yaml.go", "gopkg.in/yaml%2ev3.Marshal"); filename != "gopkg.in/yaml.v3/yaml.go" {
+		t.Fatalf("unexpected relative filename: %s", filename)
+	}
+}
+
+type stackError struct{}
+
+func (stackError) Error() string { return "stack error" }
+func (stackError) Stack() []byte { return []byte("main.main\n\tmain.go:1\n") }
+
+func TestStacktrace(t *testing.T) {
+	ml := &memoryLogger{name: "memory", level: LevelInfo}
+	e := New(ml)
+	e.SetStacktraceLevel(LevelError)
+
+	e.Warning("warning")
+	e.Error("error")
+	e.WithError(fmt.Errorf("wrapped: %w", stackError{})).Error("error with stack")
+
+	if ml.messages[0].Stack != "" {
+		t.Fatalf("warning message should not be logged with stack trace")
+	}
+
+	if !strings.HasPrefix(ml.messages[1].Stack, "github.com/derekhjray/glog.TestStacktrace\n\t") {
+		t.Fatalf("unexpected stack trace: %s", ml.messages[1].Stack)
+	}
+
+	msg := ml.messages[2]
+	if msg.Stack != "main.main\n\tmain.go:1" {
+		t.Fatalf("unexpected stack trace: %s", msg.Stack)
+	}
+
+	if text := new(TextFormatter).Format(msg); !strings.HasSuffix(text, "\n\tmain.main\n\t\tmain.go:1") {
+		t.Fatalf("unexpected text: %s", text)
+	}
+
+	if data := new(JSONFormatter).Format(msg); !strings.Contains(data, `"stacktrace":"main.main\n\tmain.go:1"`) {
+		t.Fatalf("unexpected json: %s", data)
+	}
+
+	// stack trace carried by error is logged at any level, from the first error in
+	// the order of fields
+	e.SetStacktraceLevel(LevelPanic)
+	for i := 0; i < 10; i++ {
+		e.Warningw("warning with stack", "cause", fmt.Errorf("plain"), "first", stackError{}, "second", otherStackError{})
+	}
+
+	for _, msg := range ml.messages[3:] {
+		if msg.Stack != "main.main\n\tmain.go:1" {
+			t.Fatalf("unexpected stack trace: %s", msg.Stack)
+		}
+	}
+
+	e.DisableStacktrace()
+	e.WithError(stackError{}).Error("stack trace disabled")
+	if msg := ml.messages[len(ml.messages)-1]; msg.Stack != "" {
+		t.Fatalf("unexpected stack trace: %s", msg.Stack)
+	}
+}
+
+type nilStackError struct {
+	stack []byte
+}
+
+func (e *nilStackError) Error() string { return "nil stack error" }
+func (e *nilStackError) Stack() []byte { return e.stack }
+
+// formatStackError formats stack trace after message by %+v like github.com/pkg/errors
+type formatStackError struct{}
+
+func (formatStackError) Error() string { return "format" }
+func (e formatStackError) Format(s fmt.State, verb rune) {
+	if s.Flag('+') {
+		fmt.Fprint(s, "format\nmain.format\n\tformat.go:1")
+		return
+	}
+	fmt.Fprint(s, "format")
+}
+
+func TestErrorStack(t *testing.T) {
+	ml := &memoryLogger{name: "memory", level: LevelInfo}
+	e := New(ml)
+
+	var err *nilStackError
+	e.WithError(err).Info("typed nil error")
+	e.WithError(formatStackError{}).Info("formatted stack")
+
+	if len(ml.messages) != 2 || ml.messages[0].Stack != "" {
+		t.Fatalf("unexpected messages: %+v", ml.messages)
+	}
+
+	if stack := ml.messages[1].Stack; stack != "main.format\n\tformat.go:1" {
+		t.Fatalf("unexpected stack trace: %q", stack)
+	}
+}
+
+type otherStackError struct{}
+
+func (otherStackError) Error() string { return "other" }
+func (otherStackError) Stack() string { return "main.other\n\tother.go:1" }
+
+func TestFatal(t *testing.T) {
+	if dir := os.Getenv("GLOG_TEST_FATAL"); dir != "" {
+		e := New(NewFileLogger(LevelInfo, Path(dir)))
+		RegisterExitHandler(func() {
+			e.Error("exit handler")
+		})
+		e.Fatal("fatal")
+		return
+	}
+
+	dir := t.TempDir()
+	cmd := exec.Command(os.Args[0], "-test.run", "^TestFatal$")
+	cmd.Env = append(os.Environ(), "GLOG_TEST_FATAL="+dir)
+	if err := cmd.Run(); err == nil || cmd.ProcessState.ExitCode() != 1 {
+		t.Fatalf("expect exit code 1, got %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expect 1 log file, got %d, %v", len(entries), err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	if !strings.Contains(string(data), "[F] fatal") || !strings.Contains(string(data), "[E] exit handler") {
+		t.Fatalf("fatal message is not flushed: %q", data)
+	}
+}
+
+type failingLogger struct {
+	memoryLogger
+}
+
+func (f *failingLogger) Flush() error { return fmt.Errorf("flush failed") }
+func (f *failingLogger) Close() error { return fmt.Errorf("close failed") }
+
+func TestSync(t *testing.T) {
+	dir := t.TempDir()
+	e := New(NewFileLogger(LevelInfo, Path(dir), Fsync(true)))
+	e.Info("synced")
+
+	if err := e.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	data, _ := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	if !strings.Contains(string(data), "[I] synced") {
+		t.Fatalf("message is not synced: %q", data)
+	}
+
+	e.Regist(&failingLogger{memoryLogger{name: "a"}})
+	e.Regist(&failingLogger{memoryLogger{name: "b"}})
+
+	if err := e.Sync(); err == nil || !strings.Contains(err.Error(), "flush failed") {
+		t.Fatalf("expect flush error, got %v", err)
+	}
+
+	err := e.Close()
+	if errs, ok := err.(multiError); !ok || len(errs) != 2 {
+		t.Fatalf("expect 2 close errors, got %v", err)
+	}
+}
+
+func TestFieldOrder(t *testing.T) {
+	ml := &memoryLogger{name: "memory", level: LevelInfo}
+	e := New(ml)
+
+	e.With("route", "/login").WithFields(Fields{"user": "derek", "age": 35}).Infow("ordered", "request_id", "r1", "b", 2)
+	e.Infow("pairs", "z", 1, "a", 2)
+	e.With("z", 1).With("a", 2).AddCallerSkip(0).Info("wrapper")
+
+	tf := &TextFormatter{PinnedKeys: []string{"request_id"}}
+	expected := []string{
+		" (request_id = r1, route = /login, age = 35, user = derek, b = 2)",
+		" (z = 1, a = 2)",
+		" (z = 1, a = 2)",
+	}
+
+	for i, suffix := range expected {
+		if text := tf.Format(ml.messages[i]); !strings.HasSuffix(text, suffix) {
+			t.Fatalf("expect suffix '%s', got '%s'", suffix, text)
+		}
+	}
+
+	msg := &Message{Fields: Fields{"z": 1, "b": 2, "a": 3}}
+	if keys := msg.FieldKeys(); strings.Join(keys, ",") != "a,b,z" {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	dir := t.TempDir()
+	ml := &memoryLogger{name: "memory", level: LevelInfo}
+	e := New(ml, NewFileLogger(LevelInfo, Path(dir), Redact(DefaultRedactors()...)))
+
+	e.Infow("login alice@example.com with card 4111 1111 1111 1111",
+		"password", "hunter2", "header.Authorization", "Bearer abc.def", "note", "token Bearer xyz")
+	e.Close()
+
+	if fields := ml.messages[0].Fields; fields["password"] != "hunter2" || !strings.Contains(ml.messages[0].Message, "alice") {
+		t.Fatalf("original message is modified: %+v", ml.messages[0])
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expect 1 log file, got %d, %v", len(entries), err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	for _, secret := range []string{"alice@example.com", "4111", "hunter2", "abc.def", "xyz"} {
+		if strings.Contains(string(data), secret) {
+			t.Fatalf("secret '%s' is logged: %s", secret, data)
+		}
+	}
+
+	if !strings.Contains(string(data), "note = token Bearer "+RedactedValue) {
+		t.Fatalf("unexpected log: %s", data)
+	}
+
+	msg := redact(&Message{
+		Message: "order 1234567890123 paid by 4111-1111-1111-1111",
+		Stack:   "main.login(bob@example.com)",
+		Fields: Fields{
+			"user":  struct{ Email string }{"bob@example.com"},
+			"body":  []byte("Authorization: Bearer abc"),
+			"order": 1234567890123,
+			"card":  int64(4111111111111111),
+			"error": fmt.Errorf("invalid user bob@example.com"),
+			"nil":   (*nilError)(nil),
+		},
+	}, DefaultRedactors())
+
+	if msg.Message != "order 1234567890123 paid by "+RedactedValue || strings.Contains(msg.Stack, "bob") {
+		t.Fatalf("unexpected redacted message: %+v", msg)
+	}
+
+	for key, expected := range map[string]interface{}{
+		"user":  "{" + RedactedValue + "}",
+		"body":  "Authorization: Bearer " + RedactedValue,
+		"order": 1234567890123,
+		"card":  RedactedValue,
+		"error": "invalid user " + RedactedValue,
+		"nil":   (*nilError)(nil),
+	} {
+		if msg.Fields[key] != expected {
+			t.Fatalf("expect %s redacted to %v, got %v", key, expected, msg.Fields[key])
+		}
+	}
+}
+
+func TestRotation(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	// Friday
+	now := time.Date(2022, 8, 5, 10, 30, 0, 0, shanghai)
+	for _, c := range []struct {
+		policy   string
+		duration time.Duration
+		expected time.Time
+	}{
+		{RotateHourly, 0, time.Date(2022, 8, 5, 11, 0, 0, 0, shanghai)},
+		{RotateDaily, 0, time.Date(2022, 8, 6, 0, 0, 0, 0, shanghai)},
+		{RotateWeekly, 0, time.Date(2022, 8, 8, 0, 0, 0, 0, shanghai)},
+		{RotateByDuration, 24 * time.Hour, time.Date(2022, 8, 6, 0, 0, 0, 0, shanghai)},
+		{RotateByDuration, 6 * time.Hour, time.Date(2022, 8, 5, 12, 0, 0, 0, shanghai)},
+		{RotateByDuration, 7 * time.Hour, time.Date(2022, 8, 5, 12, 0, 0, 0, shanghai)},
+		{RotateBySize, 0, time.Time{}},
+	} {
+		f := &file{rotatePolicy: c.policy, rotateDuration: c.duration, rotateLocation: shanghai, rotateWeekday: time.Monday}
+		if next := f.nextRotation(now.UTC()); !next.Equal(c.expected) {
+			t.Fatalf("%s %v expect next rotation at %v, got %v", c.policy, c.duration, c.expected, next)
+		}
+	}
+
+	// durations don't divide a day are aligned to Unix epoch
+	f := &file{rotatePolicy: RotateByDuration, rotateDuration: 7 * time.Hour, rotateLocation: time.UTC}
+	if next := f.nextRotation(time.Unix(0, 0)); !next.Equal(time.Unix(7*3600, 0)) {
+		t.Fatalf("expect next rotation at 07:00 of Unix epoch, got %v", next)
+	}
+
+	// catch up after system sleep across several boundaries
+	f = &file{rotatePolicy: RotateHourly, rotateLocation: shanghai}
+	f.rotateAt = f.nextRotation(now)
+	if f.rotateDue(now) || !f.rotateDue(now.Add(5*time.Hour)) {
+		t.Fatalf("unexpected rotation deadline %v", f.rotateAt)
+	}
+}
+
+func TestRotateBySize(t *testing.T) {
+	dir := t.TempDir()
+	e := New(NewFileLogger(LevelInfo, Path(dir), RotatePolicy(RotateDaily, RotateBySize), RotateFileSize(200), SweepFileCount(100)))
+	e.SetMode(ModeRelease)
+
+	for i := 0; i < 20; i++ {
+		e.Infof("message %02d", i)
+	}
+	e.Close()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) < 3 {
+		t.Fatalf("expect log files rotated by size, got %d, %v", len(entries), err)
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if strings.HasSuffix(path, ".log") {
+			if info, _ := entry.Info(); info.Size() > 200 {
+				t.Fatalf("log file %s exceeds size limit, %d bytes", entry.Name(), info.Size())
+			}
+			continue
+		}
+
+		fp, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		gzr, err := gzip.NewReader(fp)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if header, err := tar.NewReader(gzr).Next(); err != nil || header.Size > 200 {
+			t.Fatalf("log file %s exceeds size limit, %v", entry.Name(), err)
+		}
+		fp.Close()
+	}
+}
+
+func TestFilenamePattern(t *testing.T) {
+	format, regex, sequenced := compilePattern("app-%Y%m%d-%{pid}-%{seq}-*%%")
+	now := time.Date(2022, 8, 5, 10, 30, 0, 0, time.UTC)
+
+	name := format(now, 2)
+	if !sequenced || !strings.HasPrefix(name, fmt.Sprintf("app-20220805-%d-2-", os.Getpid())) || !strings.HasSuffix(name, "%") {
+		t.Fatalf("unexpected filename '%s'", name)
+	}
+
+	for _, stem := range []string{name, name + ".1"} {
+		if !regex.MatchString(stem) {
+			t.Fatalf("regex '%s' does not match '%s'", regex, stem)
+		}
+	}
+
+	dir := t.TempDir()
+	e := New(NewFileLogger(LevelInfo, Path(dir, "app-%Y%m%d-%{seq}"), Symlink("current.log"),
+		RotatePolicy(RotateBySize), RotateFileSize(100), SweepFileCount(100)))
+	e.SetMode(ModeRelease)
+
+	for i := 0; i < 5; i++ {
+		e.Infof("message %02d", i)
+	}
+	e.Close()
+
+	data, err := os.ReadFile(filepath.Join(dir, "current.log"))
+	if err != nil || !strings.Contains(string(data), "message 04") || strings.Contains(string(data), "message 00") {
+		t.Fatalf("current.log does not link to the active log file, %q, %v", data, err)
+	}
+
+	if !exists(dir, "app-"+time.Now().Format("20060102")+"-3.log") {
+		t.Fatalf("expect log files named with sequence number")
+	}
+}
+
+func TestRotateExternal(t *testing.T) {
+	dir := t.TempDir()
+	e := New(NewFileLogger(LevelInfo, Path(dir, "app.log"), RotatePolicy(RotateExternal, RotateBySize), ReopenSignal(syscall.SIGHUP)))
+	e.SetMode(ModeRelease)
+
+	path := filepath.Join(dir, "app.log")
+	e.Info("before rotation")
+	e.Sync()
+
+	// rotate by renaming, and notify logger to reopen log file
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+
+	process, _ := os.FindProcess(os.Getpid())
+	if err := process.Signal(syscall.SIGHUP); err != nil {
+		t.Skip(err)
+	}
+
+	for deadline := time.Now().Add(3 * time.Second); ; {
+		if _, err := os.Stat(path); err == nil {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatal("log file is not reopened on signal")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	e.Info("after rotation")
+	e.Close()
+
+	data, _ := os.ReadFile(path)
+	rotated, _ := os.ReadFile(path + ".1")
+	if !strings.Contains(string(data), "after rotation") || !strings.Contains(string(rotated), "before rotation") {
+		t.Fatalf("unexpected log files, %q, %q", data, rotated)
+	}
+
+	// detect log file deleted
+	f := &file{path: dir, filename: "app.log"}
+	if err := f.reopen(); err != nil {
+		t.Fatal(err)
+	}
+	defer f.file.Close()
+
+	if f.moved() {
+		t.Fatal("log file is not moved")
+	}
+
+	os.Remove(path)
+	if !f.moved() {
+		t.Fatal("expect log file deleted")
+	}
+}
+
+func TestCompression(t *testing.T) {
+	dir := t.TempDir()
+
+	// log file rotated and compression interrupted by crash before
+	os.WriteFile(filepath.Join(dir, "app-9.log"), []byte("rotated\n"), 0660)
+	os.WriteFile(filepath.Join(dir, "app-9.log.gz.tmp"), []byte("partial"), 0660)
+
+	e := New(NewFileLogger(LevelInfo, Path(dir, "app-%{seq}"), Compression(CompressGzip), CompressLevel(gzip.BestSpeed),
+		RotatePolicy(RotateBySize), RotateFileSize(100), SweepFileCount(100)))
+	e.SetMode(ModeRelease)
+
+	for i := 0; i < 5; i++ {
+		e.Infof("message %02d", i)
+	}
+	e.Close()
+
+	var text string
+	for _, name := range []string{"app-9.log.gz", "app-1.log.gz", "app-2.log.gz"} {
+		fp, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		gzr, err := gzip.NewReader(fp)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := io.ReadAll(gzr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		text += string(data)
+		fp.Close()
+	}
+
+	if !strings.Contains(text, "rotated") || !strings.Contains(text, "message 00") || !strings.Contains(text, "message 03") {
+		t.Fatalf("unexpected compressed logs: %q", text)
+	}
+
+	for _, name := range []string{"app-9.log", "app-9.log.gz.tmp"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			t.Fatalf("%s is not removed", name)
+		}
+	}
+}
+
+func TestSweepByInterval(t *testing.T) {
+	dir := t.TempDir()
+
+	old, recent := filepath.Join(dir, "app-1.log.gz"), filepath.Join(dir, "app-2.log.gz")
+	os.WriteFile(old, []byte("old"), 0660)
+	os.WriteFile(recent, []byte("recent"), 0660)
+	tm := time.Now().Add(-48 * time.Hour)
+	os.Chtimes(old, tm, tm)
+
+	e := New(NewFileLogger(LevelInfo, Path(dir, "app-%{seq}"), Compression(CompressGzip), RotatePolicy(RotateBySize),
+		RotateFileSize(100), SweepPolicy(SweepByInterval), SweepInterval(24*time.Hour)))
+	e.SetMode(ModeRelease)
+
+	for i := 0; i < 3; i++ {
+		e.Infof("message %02d", i)
+	}
+	e.Close()
+
+	if _, err := os.Stat(old); err == nil {
+		t.Fatalf("old archive is not removed")
+	}
+
+	if _, err := os.Stat(recent); err != nil {
+		t.Fatalf("recent archive is removed, %v", err)
+	}
+}
@@ -0,0 +1,214 @@
+package log
+
+import (
+	"fmt"
+)
+
+// key of the value without a key in keysAndValues
+const badKey = "!BADKEY"
+
+// pairs converts alternating keys and values to fields and their keys in order,
+// non-string keys are formatted by fmt.Sprint, and a trailing value without key is
+// saved with key "!BADKEY"
+func pairs(keysAndValues []interface{}) (Fields, []string) {
+	if len(keysAndValues) == 0 {
+		return nil, nil
+	}
+
+	fields := make(Fields, (len(keysAndValues)+1)/2)
+	keys := make([]string, 0, (len(keysAndValues)+1)/2)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key, value := badKey, keysAndValues[i]
+		if i < len(keysAndValues)-1 {
+			if k, ok := keysAndValues[i].(string); ok {
+				key = k
+			} else {
+				key = fmt.Sprint(keysAndValues[i])
+			}
+			value = keysAndValues[i+1]
+		}
+
+		if _, ok := fields[key]; !ok {
+			keys = append(keys, key)
+		}
+		fields[key] = value
+	}
+
+	return fields, keys
+}
+
+// Tracef print trace message formatted according to format
+func (e *Engine) Tracef(format string, args ...interface{}) {
+	e.log(LevelTrace, fmt.Sprintf(format, args...), nil, nil)
+}
+
+// Debugf print debug message formatted according to format
+func (e *Engine) Debugf(format string, args ...interface{}) {
+	e.log(LevelDebug, fmt.Sprintf(format, args...), nil, nil)
+}
+
+// Verbosef print verbose message formatted according to format
+func (e *Engine) Verbosef(format string, args ...interface{}) {
+	e.log(LevelVerbose, fmt.Sprintf(format, args...), nil, nil)
+}
+
+// Infof print information message formatted according to format
+func (e *Engine) Infof(format string, args ...interface{}) {
+	e.log(LevelInfo, fmt.Sprintf(format, args...), nil, nil)
+}
+
+// Warningf print warning message formatted according to format
+func (e *Engine) Warningf(format string, args ...interface{}) {
+	e.log(LevelWarn, fmt.Sprintf(format, args...), nil, nil)
+}
+
+// Errorf print error message formatted according to format
+func (e *Engine) Errorf(format string, args ...interface{}) {
+	e.log(LevelError, fmt.Sprintf(format, args...), nil, nil)
+}
+
+// Fatalf print fatal error message formatted according to format, and app will quit
+func (e *Engine) Fatalf(format string, args ...interface{}) {
+	e.log(LevelFatal, fmt.Sprintf(format, args...), nil, nil)
+}
+
+// Panicf print panic message formatted according to format, and app will panic
+func (e *Engine) Panicf(format string, args ...interface{}) {
+	e.log(LevelPanic, fmt.Sprintf(format, args...), nil, nil)
+}
+
+// Tracew print trace message with alternating keys and values as fields
+func (e *Engine) Tracew(msg string, keysAndValues ...interface{}) {
+	fields, keys := pairs(keysAndValues)
+	e.log(LevelTrace, msg, fields, keys)
+}
+
+// Debugw print debug message with alternating keys and values as fields
+func (e *Engine) Debugw(msg string, keysAndValues ...interface{}) {
+	fields, keys := pairs(keysAndValues)
+	e.log(LevelDebug, msg, fields, keys)
+}
+
+// Verbosew print verbose message with alternating keys and values as fields
+func (e *Engine) Verbosew(msg string, keysAndValues ...interface{}) {
+	fields, keys := pairs(keysAndValues)
+	e.log(LevelVerbose, msg, fields, keys)
+}
+
+// Infow print information message with alternating keys and values as fields
+func (e *Engine) Infow(msg string, keysAndValues ...interface{}) {
+	fields, keys := pairs(keysAndValues)
+	e.log(LevelInfo, msg, fields, keys)
+}
+
+// Warningw print warning message with alternating keys and values as fields
+func (e *Engine) Warningw(msg string, keysAndValues ...interface{}) {
+	fields, keys := pairs(keysAndValues)
+	e.log(LevelWarn, msg, fields, keys)
+}
+
+// Errorw print error message with alternating keys and values as fields
+func (e *Engine) Errorw(msg string, keysAndValues ...interface{}) {
+	fields, keys := pairs(keysAndValues)
+	e.log(LevelError, msg, fields, keys)
+}
+
+// Fatalw print fatal error message with alternating keys and values as fields, and app will quit
+func (e *Engine) Fatalw(msg string, keysAndValues ...interface{}) {
+	fields, keys := pairs(keysAndValues)
+	e.log(LevelFatal, msg, fields, keys)
+}
+
+// Panicw print panic message with alternating keys and values as fields, and app will panic
+func (e *Engine) Panicw(msg string, keysAndValues ...interface{}) {
+	fields, keys := pairs(keysAndValues)
+	e.log(LevelPanic, msg, fields, keys)
+}
+
+// Tracef print trace message formatted according to format
+func Tracef(format string, args ...interface{}) {
+	logctx.log(LevelTrace, fmt.Sprintf(format, args...), nil, nil)
+}
+
+// Debugf print debug message formatted according to format
+func Debugf(format string, args ...interface{}) {
+	logctx.log(LevelDebug, fmt.Sprintf(format, args...), nil, nil)
+}
+
+// Verbosef print verbose message formatted according to format
+func Verbosef(format string, args ...interface{}) {
+	logctx.log(LevelVerbose, fmt.Sprintf(format, args...), nil, nil)
+}
+
+// Infof print information message formatted according to format
+func Infof(format string, args ...interface{}) {
+	logctx.log(LevelInfo, fmt.Sprintf(format, args...), nil, nil)
+}
+
+// Warningf print warning message formatted according to format
+func Warningf(format string, args ...interface{}) {
+	logctx.log(LevelWarn, fmt.Sprintf(format, args...), nil, nil)
+}
+
+// Errorf print error message formatted according to format
+func Errorf(format string, args ...interface{}) {
+	logctx.log(LevelError, fmt.Sprintf(format, args...), nil, nil)
+}
+
+// Fatalf print fatal error message formatted according to format, and app will quit
+func Fatalf(format string, args ...interface{}) {
+	logctx.log(LevelFatal, fmt.Sprintf(format, args...), nil, nil)
+}
+
+// Panicf print panic message formatted according to format, and app will panic
+func Panicf(format string, args ...interface{}) {
+	logctx.log(LevelPanic, fmt.Sprintf(format, args...), nil, nil)
+}
+
+// Tracew print trace message with alternating keys and values as fields
+func Tracew(msg string, keysAndValues ...interface{}) {
+	fields, keys := pairs(keysAndValues)
+	logctx.log(LevelTrace, msg, fields, keys)
+}
+
+// Debugw print debug message with alternating keys and values as fields
+func Debugw(msg string, keysAndValues ...interface{}) {
+	fields, keys := pairs(keysAndValues)
+	logctx.log(LevelDebug, msg, fields, keys)
+}
+
+// Verbosew print verbose message with alternating keys and values as fields
+func Verbosew(msg string, keysAndValues ...interface{}) {
+	fields, keys := pairs(keysAndValues)
+	logctx.log(LevelVerbose, msg, fields, keys)
+}
+
+// Infow print information message with alternating keys and values as fields
+func Infow(msg string, keysAndValues ...interface{}) {
+	fields, keys := pairs(keysAndValues)
+	logctx.log(LevelInfo, msg, fields, keys)
+}
+
+// Warningw print warning message with alternating keys and values as fields
+func Warningw(msg string, keysAndValues ...interface{}) {
+	fields, keys := pairs(keysAndValues)
+	logctx.log(LevelWarn, msg, fields, keys)
+}
+
+// Errorw print error message with alternating keys and values as fields
+func Errorw(msg string, keysAndValues ...interface{}) {
+	fields, keys := pairs(keysAndValues)
+	logctx.log(LevelError, msg, fields, keys)
+}
+
+// Fatalw print fatal error message with alternating keys and values as fields, and app will quit
+func Fatalw(msg string, keysAndValues ...interface{}) {
+	fields, keys := pairs(keysAndValues)
+	logctx.log(LevelFatal, msg, fields, keys)
+}
+
+// Panicw print panic message with alternating keys and values as fields, and app will panic
+func Panicw(msg string, keysAndValues ...interface{}) {
+	fields, keys := pairs(keysAndValues)
+	logctx.log(LevelPanic, msg, fields, keys)
+}
@@ -0,0 +1,187 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Keys of LogfmtFormatter
+const (
+	LogfmtTimeKey       = "ts"
+	LogfmtLevelKey      = "level"
+	LogfmtMessageKey    = "msg"
+	LogfmtCallerKey     = "caller"
+	LogfmtStacktraceKey = "stacktrace"
+)
+
+// LogfmtFormatter formats message in logfmt, e.g.
+//
+//	ts=2022-08-05T10:00:00Z level=info msg="hello world" caller=log.go:42 user=derek
+//
+// Fields are written in the order of Message.FieldKeys, and prefixed by "fields." if
+// they conflict with keys of message. Values are quoted if they contain spaces, quotes,
+// equal signs or control characters.
+type LogfmtFormatter struct {
+	// PinnedKeys are keys of fields written before other fields
+	PinnedKeys []string
+
+	// Time is format of timestamp, default time.RFC3339Nano
+	Time TimeFormat
+
+	bp bufferPool
+}
+
+func (lf *LogfmtFormatter) Format(msg *Message) (message string) {
+	var (
+		buf = lf.bp.acquire()
+		num [64]byte
+	)
+
+	buf.WriteString(LogfmtTimeKey)
+	buf.WriteByte('=')
+	writeLogfmtValue(buf, string(lf.Time.appendTime(num[:0], msg.Timestamp, time.RFC3339Nano)))
+	buf.WriteByte(' ')
+	buf.WriteString(LogfmtLevelKey)
+	buf.WriteByte('=')
+	buf.WriteString(strings.ToLower(msg.Level.String()))
+	buf.WriteByte(' ')
+	buf.WriteString(LogfmtMessageKey)
+	buf.WriteByte('=')
+	writeLogfmtValue(buf, msg.Message)
+
+	if msg.Filename != "" {
+		buf.WriteByte(' ')
+		buf.WriteString(LogfmtCallerKey)
+		buf.WriteByte('=')
+		writeLogfmtValue(buf, msg.Filename+":"+strconv.Itoa(msg.Line))
+	}
+
+	for _, key := range msg.FieldKeys(lf.PinnedKeys...) {
+		value := msg.Fields[key]
+		switch key {
+		case LogfmtTimeKey, LogfmtLevelKey, LogfmtMessageKey, LogfmtCallerKey, LogfmtStacktraceKey:
+			key = conflictKey(key, msg.Fields)
+		}
+
+		buf.WriteByte(' ')
+		writeLogfmtKey(buf, key)
+		buf.WriteByte('=')
+		writeLogfmtValue(buf, logfmtString(value))
+	}
+
+	if msg.Stack != "" {
+		buf.WriteByte(' ')
+		buf.WriteString(LogfmtStacktraceKey)
+		buf.WriteByte('=')
+		writeLogfmtValue(buf, msg.Stack)
+	}
+
+	message = buf.String()
+	lf.bp.recycle(buf)
+
+	return message
+}
+
+// logfmtString converts value to string written in logfmt
+func logfmtString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		// errors and Stringers are printed by fmt, which prints "<nil>" for nil
+		// pointers and recovers from panics of their methods
+		return fmt.Sprint(v)
+	}
+}
+
+// writeLogfmtKey writes key with spaces, quotes, equal signs and control characters
+// replaced by underscores
+func writeLogfmtKey(buf *bytes.Buffer, key string) {
+	if key == "" {
+		buf.WriteByte('_')
+		return
+	}
+
+	for _, r := range key {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError || r == 0x7f {
+			buf.WriteByte('_')
+		} else {
+			buf.WriteRune(r)
+		}
+	}
+}
+
+// writeLogfmtValue writes value, quotes it if it's empty or contains spaces, quotes,
+// equal signs or control characters
+func writeLogfmtValue(buf *bytes.Buffer, value string) {
+	if value != "" && strings.IndexFunc(value, needsQuote) < 0 {
+		buf.WriteString(value)
+		return
+	}
+
+	buf.WriteByte('"')
+	start := 0
+	for i := 0; i < len(value); {
+		b := value[i]
+		if b >= utf8.RuneSelf {
+			if r, size := utf8.DecodeRuneInString(value[i:]); r == utf8.RuneError && size == 1 {
+				buf.WriteString(value[start:i])
+				buf.WriteString("\ufffd")
+				i++
+				start = i
+			} else {
+				i += size
+			}
+			continue
+		}
+
+		if b >= ' ' && b != '"' && b != '\\' && b != 0x7f {
+			i++
+			continue
+		}
+
+		buf.WriteString(value[start:i])
+		switch b {
+		case '"', '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(b)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			buf.WriteString(`\u00`)
+			buf.WriteByte(hex[b>>4])
+			buf.WriteByte(hex[b&0xf])
+		}
+		i++
+		start = i
+	}
+
+	buf.WriteString(value[start:])
+	buf.WriteByte('"')
+}
+
+func needsQuote(r rune) bool {
+	return r <= ' ' || r == '=' || r == '"' || r == '\\' || r == 0x7f || r == utf8.RuneError
+}
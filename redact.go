@@ -0,0 +1,202 @@
+package log
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// RedactedValue replaces sensitive data redacted
+const RedactedValue = "[REDACTED]"
+
+// DefaultRedactedKeys are keys of fields redacted by RedactKeys if no key is given
+var DefaultRedactedKeys = []string{"password", "token", "authorization"}
+
+// Patterns of sensitive data in message text and fields, CreditCardPattern matches
+// any number of card length, RedactCreditCards redacts only those pass Luhn check
+var (
+	CreditCardPattern  = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+	EmailPattern       = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	BearerTokenPattern = regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9\-._~+/]+=*`)
+)
+
+// Redactor redacts sensitive data of message before it's formatted, message passed
+// to Redact is a copy owned by the logger, its Fields can be modified without
+// affecting the original message or other loggers.
+type Redactor interface {
+	Redact(msg *Message)
+}
+
+// RedactorFunc is an adapter to use ordinary function as Redactor
+type RedactorFunc func(msg *Message)
+
+func (fn RedactorFunc) Redact(msg *Message) {
+	fn(msg)
+}
+
+// Redact redacts messages by redactors in order before they're formatted by the
+// logger, e.g. Redact(DefaultRedactors()...)
+func Redact(redactors ...Redactor) Option {
+	return func(l Logger) {
+		switch lg := l.(type) {
+		case *console:
+			lg.redactors = append(lg.redactors, redactors...)
+		case *file:
+			lg.redactors = append(lg.redactors, redactors...)
+		case *syslog:
+			lg.redactors = append(lg.redactors, redactors...)
+		}
+	}
+}
+
+// DefaultRedactors returns redactors of DefaultRedactedKeys, credit card numbers,
+// emails and bearer tokens
+func DefaultRedactors() []Redactor {
+	return []Redactor{
+		RedactKeys(),
+		RedactCreditCards(),
+		RedactPattern(EmailPattern, RedactedValue),
+		RedactPattern(BearerTokenPattern, "${1}"+RedactedValue),
+	}
+}
+
+// keyRedactor replaces values of fields with keys in deny-list
+type keyRedactor struct {
+	keys map[string]struct{}
+}
+
+// RedactKeys returns a Redactor replaces values of fields by RedactedValue if keys
+// of fields, or the last part of keys qualified by dot like "header.authorization",
+// equal to any of keys case-insensitively, DefaultRedactedKeys are used if no key
+// is given
+func RedactKeys(keys ...string) Redactor {
+	if len(keys) == 0 {
+		keys = DefaultRedactedKeys
+	}
+
+	kr := &keyRedactor{keys: make(map[string]struct{}, len(keys))}
+	for _, key := range keys {
+		kr.keys[strings.ToLower(key)] = struct{}{}
+	}
+
+	return kr
+}
+
+func (kr *keyRedactor) Redact(msg *Message) {
+	for key := range msg.Fields {
+		name := strings.ToLower(key)
+		if index := strings.LastIndexByte(name, '.'); index >= 0 {
+			if _, ok := kr.keys[name[index+1:]]; ok {
+				msg.Fields[key] = RedactedValue
+				continue
+			}
+		}
+
+		if _, ok := kr.keys[name]; ok {
+			msg.Fields[key] = RedactedValue
+		}
+	}
+}
+
+// patternRedactor replaces text matched by pattern
+type patternRedactor struct {
+	pattern     *regexp.Regexp
+	replacement string
+	valid       func(match string) bool // reports whether match is sensitive, nil if all are
+}
+
+// RedactPattern returns a Redactor replaces text matched by pattern in message, stack
+// trace and values of fields with replacement, in which $ signs are expanded as
+// regexp.Regexp.ReplaceAllString does. Values other than strings are matched in the
+// form printed by fmt's %v, and replaced by the redacted text if matched.
+func RedactPattern(pattern *regexp.Regexp, replacement string) Redactor {
+	return &patternRedactor{pattern: pattern, replacement: replacement}
+}
+
+// RedactCreditCards returns a Redactor replaces numbers matched by CreditCardPattern
+// and passing Luhn check with RedactedValue
+func RedactCreditCards() Redactor {
+	return &patternRedactor{pattern: CreditCardPattern, replacement: RedactedValue, valid: luhn}
+}
+
+func (pr *patternRedactor) Redact(msg *Message) {
+	msg.Message = pr.replace(msg.Message)
+	msg.Stack = pr.replace(msg.Stack)
+
+	for key, value := range msg.Fields {
+		var text string
+		switch v := value.(type) {
+		case nil:
+			continue
+		case string:
+			text = v
+		case []byte:
+			text = string(v)
+		default:
+			// fmt prints errors and Stringers, and tolerates their nil pointers
+			text = fmt.Sprint(v)
+		}
+
+		if redacted := pr.replace(text); redacted != text {
+			msg.Fields[key] = redacted
+		}
+	}
+}
+
+// replace replaces sensitive text matched by pattern in text
+func (pr *patternRedactor) replace(text string) string {
+	if pr.valid == nil {
+		return pr.pattern.ReplaceAllString(text, pr.replacement)
+	}
+
+	return pr.pattern.ReplaceAllStringFunc(text, func(match string) string {
+		if !pr.valid(match) {
+			return match
+		}
+		return pr.pattern.ReplaceAllString(match, pr.replacement)
+	})
+}
+
+// luhn reports whether digits in number pass Luhn check, other characters like
+// spaces and dashes are ignored
+func luhn(number string) bool {
+	var sum, digits int
+	for i := len(number) - 1; i >= 0; i-- {
+		ch := number[i]
+		if ch < '0' || ch > '9' {
+			continue
+		}
+
+		d := int(ch - '0')
+		if digits%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+	}
+
+	return digits > 0 && sum%10 == 0
+}
+
+// redact returns a copy of msg redacted by redactors, or msg if there is no redactor
+func redact(msg *Message, redactors []Redactor) *Message {
+	if len(redactors) == 0 {
+		return msg
+	}
+
+	clone := *msg
+	if msg.Fields != nil {
+		clone.Fields = make(Fields, len(msg.Fields))
+		for key, value := range msg.Fields {
+			clone.Fields[key] = value
+		}
+	}
+
+	for _, redactor := range redactors {
+		redactor.Redact(&clone)
+	}
+
+	return &clone
+}
@@ -0,0 +1,192 @@
+//go:build go1.21
+
+package log
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"time"
+)
+
+// slogHandler is a slog.Handler dispatches records to loggers of engine
+type slogHandler struct {
+	engine *Engine
+	fields Fields   // attributes added by WithAttrs, keys are qualified by groups
+	keys   []string // keys of fields in the order they were added
+	prefix string   // prefix of subsequent attribute keys, e.g. "request.header."
+}
+
+// SlogHandler returns a slog.Handler converts records to messages and dispatches them
+// to loggers of engine. Attribute keys in groups are joined by dot, e.g. "request.id",
+// and records of levels above slog.LevelError are logged as LevelFatal or LevelPanic
+// without exiting or panicking.
+func (e *Engine) SlogHandler() slog.Handler {
+	return &slogHandler{engine: e}
+}
+
+// Slog returns a slog.Logger writes to loggers of engine
+func (e *Engine) Slog() *slog.Logger {
+	return slog.New(e.SlogHandler())
+}
+
+// SlogHandler returns a slog.Handler of log engine
+func SlogHandler() slog.Handler {
+	return logctx.SlogHandler()
+}
+
+// Slog returns a slog.Logger writes to loggers of log engine
+func Slog() *slog.Logger {
+	return logctx.Slog()
+}
+
+// fromSlogLevel maps slog levels to log levels, levels between slog's predefined
+// levels are mapped to Verbose or the less severe level
+func fromSlogLevel(level slog.Level) Level {
+	switch {
+	case level < slog.LevelDebug:
+		return LevelTrace
+	case level < slog.LevelDebug+2:
+		return LevelDebug
+	case level < slog.LevelInfo:
+		return LevelVerbose
+	case level < slog.LevelWarn:
+		return LevelInfo
+	case level < slog.LevelError:
+		return LevelWarn
+	case level < slog.LevelError+4:
+		return LevelError
+	case level < slog.LevelError+8:
+		return LevelFatal
+	default:
+		return LevelPanic
+	}
+}
+
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.engine.enabled(fromSlogLevel(level))
+}
+
+func (h *slogHandler) Handle(_ context.Context, r slog.Record) error {
+	if r.Message == "" {
+		return nil
+	}
+
+	msg := &Message{
+		Level:     fromSlogLevel(r.Level),
+		Message:   r.Message,
+		Timestamp: r.Time,
+	}
+
+	if msg.Timestamp.IsZero() {
+		msg.Timestamp = time.Now()
+	}
+
+	if len(h.fields) > 0 || r.NumAttrs() > 0 {
+		msg.Fields = make(Fields, len(h.fields)+r.NumAttrs())
+		for key, value := range h.fields {
+			msg.Fields[key] = value
+		}
+
+		keys := make([]string, len(h.keys), len(h.keys)+r.NumAttrs())
+		copy(keys, h.keys)
+		r.Attrs(func(attr slog.Attr) bool {
+			keys = addAttr(msg.Fields, keys, h.prefix, attr)
+			return true
+		})
+		msg.keys = keys
+	}
+
+	if r.PC != 0 && h.engine.withCaller(msg.Level) {
+		h.engine.setCaller(msg, r.PC)
+	}
+
+	// Handle is called by slog.Logger.log called by the function called by user,
+	// e.g. slog.Logger.Info, unless handler is wrapped by other handlers
+	skip := 2
+	if r.PC != 0 && h.engine.withStacktrace(msg.Level) {
+		skip = callerSkip(r.PC)
+	}
+
+	h.engine.outputMessage(msg, skip)
+	return nil
+}
+
+// callerSkip returns the number of stack frames from the caller of Handle to the
+// frame of pc, which calls slog.Logger functions
+func callerSkip(pc uintptr) int {
+	var pcs [maxStackDepth]uintptr
+	n := runtime.Callers(3, pcs[:])
+	for i := 0; i < n; i++ {
+		if pcs[i] == pc {
+			return i
+		}
+	}
+
+	return 2
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+
+	fields := make(Fields, len(h.fields)+len(attrs))
+	for key, value := range h.fields {
+		fields[key] = value
+	}
+
+	keys := make([]string, len(h.keys), len(h.keys)+len(attrs))
+	copy(keys, h.keys)
+	for _, attr := range attrs {
+		keys = addAttr(fields, keys, h.prefix, attr)
+	}
+
+	return &slogHandler{
+		engine: h.engine,
+		fields: fields,
+		keys:   keys,
+		prefix: h.prefix,
+	}
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	return &slogHandler{
+		engine: h.engine,
+		fields: h.fields,
+		keys:   h.keys,
+		prefix: h.prefix + name + ".",
+	}
+}
+
+// addAttr adds attr to fields and its key to keys, attributes in group are added
+// with keys qualified by group names
+func addAttr(fields Fields, keys []string, prefix string, attr slog.Attr) []string {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return keys
+	}
+
+	if attr.Value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+		}
+
+		for _, a := range attr.Value.Group() {
+			keys = addAttr(fields, keys, prefix, a)
+		}
+		return keys
+	}
+
+	key := prefix + attr.Key
+	if _, ok := fields[key]; !ok {
+		keys = append(keys, key)
+	}
+	fields[key] = attr.Value.Any()
+
+	return keys
+}
@@ -0,0 +1,61 @@
+//go:build go1.21
+
+package log
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSlogHandler(t *testing.T) {
+	ml := &memoryLogger{name: "memory", level: LevelInfo}
+	e := New(ml)
+	e.SetMode(ModeRelease)
+
+	logger := e.Slog().With("service", "api").WithGroup("request")
+	logger.Debug("dropped")
+	logger.Info("handled", "id", 1, slog.Group("user", "name", "derek"))
+	logger.Log(context.Background(), slog.LevelError+4, "fatal")
+
+	if len(ml.messages) != 2 {
+		t.Fatalf("expect 2 messages, got %d", len(ml.messages))
+	}
+
+	msg := ml.messages[0]
+	if msg.Level != LevelInfo || msg.Message != "handled" {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+
+	if len(msg.Fields) != 3 || msg.Fields["service"] != "api" || msg.Fields["request.id"] != int64(1) || msg.Fields["request.user.name"] != "derek" {
+		t.Fatalf("unexpected fields: %v", msg.Fields)
+	}
+
+	if ml.messages[1].Level != LevelFatal {
+		t.Fatalf("expect level %v, got %v", LevelFatal, ml.messages[1].Level)
+	}
+}
+
+func TestSlogStacktrace(t *testing.T) {
+	ml := &memoryLogger{name: "memory", level: LevelInfo}
+	e := New(ml)
+	e.SetStacktraceLevel(LevelError)
+
+	logger := e.Slog()
+	logger.Info("")
+	logger.Warn("warning", "error", stackError{})
+	logger.Error("error")
+
+	if len(ml.messages) != 2 {
+		t.Fatalf("expect 2 messages, got %d", len(ml.messages))
+	}
+
+	if ml.messages[0].Stack != "main.main\n\tmain.go:1" {
+		t.Fatalf("unexpected stack trace: %s", ml.messages[0].Stack)
+	}
+
+	if !strings.HasPrefix(ml.messages[1].Stack, "github.com/derekhjray/glog.TestSlogStacktrace\n\t") {
+		t.Fatalf("unexpected stack trace: %s", ml.messages[1].Stack)
+	}
+}
@@ -0,0 +1,162 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+const maxStackDepth = 64
+
+// SetStacktraceLevel sets level at or above which messages are logged with stack
+// trace of caller, default only panic messages are logged with stack trace. If an
+// error in message's fields carries stack trace, it's logged at any level instead
+// of the stack of caller, unless stack trace is disabled by DisableStacktrace.
+func (e *Engine) SetStacktraceLevel(level Level) {
+	atomic.StoreUint32(&e.stackLevel, uint32(level)+1)
+}
+
+// DisableStacktrace stops logging messages with stack trace
+func (e *Engine) DisableStacktrace() {
+	atomic.StoreUint32(&e.stackLevel, 0)
+}
+
+// SetStacktraceLevel sets level at or above which messages are logged with stack
+// trace of log engine
+func SetStacktraceLevel(level Level) {
+	logctx.SetStacktraceLevel(level)
+}
+
+// DisableStacktrace stops logging messages with stack trace of log engine
+func DisableStacktrace() {
+	logctx.DisableStacktrace()
+}
+
+// withStacktrace reports whether messages of level are logged with stack trace
+func (e *Engine) withStacktrace(level Level) bool {
+	return uint32(level) < atomic.LoadUint32(&e.stackLevel)
+}
+
+// stacktrace returns stack trace of the goroutine, skip is the number of stack frames
+// to skip with 0 identifying the caller of stacktrace
+func stacktrace(skip int) string {
+	var pcs [maxStackDepth]uintptr
+	n := runtime.Callers(skip+2, pcs[:])
+	return formatFrames(pcs[:n])
+}
+
+// formatFrames formats program counters the same way as runtime/debug.Stack does
+func formatFrames(pcs []uintptr) string {
+	var sb strings.Builder
+
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		if frame.PC != 0 {
+			if sb.Len() > 0 {
+				sb.WriteByte('\n')
+			}
+
+			sb.WriteString(frame.Function)
+			sb.WriteString("\n\t")
+			sb.WriteString(frame.File)
+			sb.WriteByte(':')
+			sb.WriteString(strconv.Itoa(frame.Line))
+		}
+
+		if !more {
+			break
+		}
+	}
+
+	return sb.String()
+}
+
+// errorStack returns the stack trace carried by the innermost error which has one,
+// of the first error field in the order of Message.FieldKeys, errors are unwrapped
+// by errors.Unwrap
+func errorStack(msg *Message) string {
+	found := false
+	for _, value := range msg.Fields {
+		if _, found = value.(error); found {
+			break
+		}
+	}
+
+	// ordered keys are only built if there is any error
+	if !found {
+		return ""
+	}
+
+	for _, key := range msg.FieldKeys() {
+		err, ok := msg.Fields[key].(error)
+		if !ok {
+			continue
+		}
+
+		var stack string
+		for ; err != nil; err = errors.Unwrap(err) {
+			if s := stackOf(err); s != "" {
+				stack = s
+			}
+		}
+
+		if stack != "" {
+			return stack
+		}
+	}
+
+	return ""
+}
+
+// stackOf returns stack trace of err if it has a method returns stack trace, e.g.
+// errors created by github.com/go-errors/errors, or it formats stack trace after
+// its message by %+v, e.g. errors created by github.com/pkg/errors. Nil pointer
+// errors and errors panic in these methods have no stack trace.
+func stackOf(err error) (stack string) {
+	if isNil(err) {
+		return ""
+	}
+
+	defer func() {
+		if recover() != nil {
+			stack = ""
+		}
+	}()
+
+	switch e := err.(type) {
+	case interface{ Stack() []byte }:
+		return strings.TrimSpace(string(e.Stack()))
+	case interface{ Stack() string }:
+		return strings.TrimSpace(e.Stack())
+	case interface{ StackTrace() string }:
+		return strings.TrimSpace(e.StackTrace())
+	case interface{ Callers() []uintptr }:
+		return formatFrames(e.Callers())
+	case fmt.Formatter:
+		message := err.Error()
+		if detail := fmt.Sprintf("%+v", err); strings.HasPrefix(detail, message+"\n") {
+			return strings.TrimSpace(detail[len(message):])
+		}
+	}
+
+	return ""
+}
+
+// isNil reports whether value is nil or a nil pointer, map, slice, etc.
+func isNil(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+
+	switch v := reflect.ValueOf(value); v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan, reflect.Interface:
+		return v.IsNil()
+	}
+
+	return false
+}
@@ -0,0 +1,122 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	stdlog "log"
+	"sync"
+)
+
+// writer writes each line as a message of level to loggers of engine
+type writer struct {
+	engine *Engine
+	level  Level
+	skip   int // extra stack frames to skip when finding caller
+
+	mu  sync.Mutex
+	buf []byte // data not terminated by newline yet
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	data := p
+	for len(data) > 0 {
+		index := bytes.IndexByte(data, '\n')
+		if index < 0 {
+			w.buf = append(w.buf, data...)
+			break
+		}
+
+		line := data[:index]
+		if len(w.buf) > 0 {
+			line = append(w.buf, line...)
+			w.buf = w.buf[:0]
+		}
+		data = data[index+1:]
+
+		w.engine.output(w.skip, w.level, string(bytes.TrimSuffix(line, []byte{'\r'})), nil, nil)
+	}
+
+	return len(p), nil
+}
+
+// Sync writes data not terminated by newline as a message
+func (w *writer) Sync() error {
+	return w.flush(w.skip + 1)
+}
+
+// Close writes data not terminated by newline as a message
+func (w *writer) Close() error {
+	return w.flush(w.skip + 1)
+}
+
+// flush writes buffered data as a message, skip is frames to skip when finding
+// caller relative to Write
+func (w *writer) flush(skip int) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) > 0 {
+		w.engine.output(skip, w.level, string(bytes.TrimSuffix(w.buf, []byte{'\r'})), nil, nil)
+		w.buf = w.buf[:0]
+	}
+
+	return nil
+}
+
+// Writer returns an io.WriteCloser writes each line as a message of level, empty
+// lines are ignored. Data not terminated by newline is kept until the rest of the
+// line is written, or written as a message by Close, or Sync of the writer.
+func (e *Engine) Writer(level Level) io.WriteCloser {
+	// output is called by Write directly, so skip one less frame to report the
+	// caller of Write
+	return &writer{engine: e, level: level, skip: -1}
+}
+
+// stdWriter returns a writer reports callers of standard library logger, which
+// calls Write by Logger.Output (or Logger.output) called by its Print functions
+func (e *Engine) stdWriter(level Level) io.Writer {
+	return &writer{engine: e, level: level, skip: 1}
+}
+
+// NewStdLogger returns a standard library logger writes messages of level to loggers
+// of engine, e.g. http.Server.ErrorLog
+func (e *Engine) NewStdLogger(level Level) *stdlog.Logger {
+	return stdlog.New(e.stdWriter(level), "", 0)
+}
+
+// RedirectStdLog redirects output of standard library log package to engine as
+// messages of level, and returns a function restores the previous output.
+func (e *Engine) RedirectStdLog(level Level) func() {
+	flags, prefix, output := stdlog.Flags(), stdlog.Prefix(), stdlog.Writer()
+
+	stdlog.SetFlags(0)
+	stdlog.SetPrefix("")
+	stdlog.SetOutput(e.stdWriter(level))
+
+	return func() {
+		stdlog.SetFlags(flags)
+		stdlog.SetPrefix(prefix)
+		stdlog.SetOutput(output)
+	}
+}
+
+// Writer returns an io.WriteCloser writes each line as a message of level to log
+// engine
+func Writer(level Level) io.WriteCloser {
+	return logctx.Writer(level)
+}
+
+// NewStdLogger returns a standard library logger writes messages of level to log
+// engine
+func NewStdLogger(level Level) *stdlog.Logger {
+	return logctx.NewStdLogger(level)
+}
+
+// RedirectStdLog redirects output of standard library log package to log engine as
+// messages of level, and returns a function restores the previous output.
+func RedirectStdLog(level Level) func() {
+	return logctx.RedirectStdLog(level)
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 func NewSysLogger(level Level, address string, options ...Option) Logger {
@@ -14,7 +15,8 @@ func NewSysLogger(level Level, address string, options ...Option) Logger {
 	)
 
 	l := &syslog{
-		level:  level,
+		name:   Syslog,
+		level:  uint32(level),
 		closed: 1,
 	}
 
@@ -29,12 +31,14 @@ func NewSysLogger(level Level, address string, options ...Option) Logger {
 	}
 
 	if l.writer, err = slog.Dial(network, address, slog.LOG_DEBUG, ""); err != nil {
-		Error("Create syslog logger failed, %v", err)
+		Errorf("Create syslog logger failed, %v", err)
 		return nil
 	}
 
 	l.messages = make(chan *Message, BufferCapacity)
+	l.syncs = make(chan chan error)
 	l.closeNotify = make(chan struct{})
+	l.done = make(chan struct{})
 
 	for _, option := range options {
 		option(l)
@@ -49,17 +53,34 @@ func NewSysLogger(level Level, address string, options ...Option) Logger {
 	return l
 }
 
+// SyslogTime prefixes messages of syslog logger in default format with timestamp in
+// format, e.g. SyslogTime(TimeFormat{Location: time.UTC}) for UTC time in RFC3339Nano,
+// syslog daemon adds time of its own, which may be in a different timezone
+func SyslogTime(format TimeFormat) Option {
+	return func(l Logger) {
+		if sl, ok := l.(*syslog); ok {
+			sl.time = &format
+		}
+	}
+}
+
 type syslog struct {
-	level       Level
+	name        string
+	level       uint32 // Level, accessed atomically
 	writer      *slog.Writer
 	messages    chan *Message
-	formatter   Formatter
+	syncs       chan chan error
+	formatter   formatterValue
+	redactors   []Redactor
+	time        *TimeFormat // format of timestamp in default format, omitted if nil
 	closeNotify chan struct{}
+	done        chan struct{}
+	closeErr    error // error of writing pending messages, set before done closed
 	closed      uint32
 }
 
 func (l *syslog) Name() string {
-	return Syslog
+	return l.name
 }
 
 func (l *syslog) Write(msg *Message) {
@@ -67,11 +88,18 @@ func (l *syslog) Write(msg *Message) {
 		return
 	}
 
-	l.messages <- msg
+	select {
+	case l.messages <- msg:
+	case <-l.closeNotify:
+	}
 }
 
 func (l *syslog) Level() Level {
-	return l.level
+	return Level(atomic.LoadUint32(&l.level))
+}
+
+func (l *syslog) SetLevel(level Level) {
+	atomic.StoreUint32(&l.level, uint32(level))
 }
 
 func (l *syslog) Close() error {
@@ -80,13 +108,13 @@ func (l *syslog) Close() error {
 	}
 
 	close(l.closeNotify)
-	close(l.messages)
+	<-l.done
 
-	if l.writer != nil {
-		return l.writer.Close()
+	if err := l.writer.Close(); err != nil {
+		return err
 	}
 
-	return nil
+	return l.closeErr
 }
 
 func (l *syslog) Format(msg *Message) string {
@@ -94,20 +122,61 @@ func (l *syslog) Format(msg *Message) string {
 		return ""
 	}
 
-	if l.formatter == nil {
+	msg = redact(msg, l.redactors)
+	formatter := l.formatter.Load()
+	if formatter == nil {
+		message := msg.Message
+		if msg.Stack != "" {
+			message += "\n" + msg.Stack
+		}
+
+		tag := msg.Level.Tag()
+		if l.time != nil {
+			var num [64]byte
+			tag = string(l.time.appendTime(num[:0], msg.Timestamp, time.RFC3339Nano)) + " " + tag
+		}
+
 		if msg.Filename != "" && msg.Function != "" {
-			return fmt.Sprintf("%s [%s:%d - %s] %s", msg.Level.Tag(), msg.Filename, msg.Line, msg.Function, msg.Message)
+			return fmt.Sprintf("%s [%s:%d - %s] %s", tag, msg.Filename, msg.Line, msg.Function, message)
 		}
 
-		return fmt.Sprintf("%s %s", msg.Level.Tag(), msg.Message)
+		return fmt.Sprintf("%s %s", tag, message)
 	}
 
-	return l.formatter.Format(msg)
+	return formatter.Format(msg)
 }
 
-func (l *syslog) write(msg *Message) {
+func (l *syslog) write(msg *Message) error {
 	msgstr := l.Format(msg)
-	l.writer.Write([]byte(msgstr))
+	_, err := l.writer.Write([]byte(msgstr))
+	return err
+}
+
+// drain writes messages remained in channel, and returns the last error
+func (l *syslog) drain() (err error) {
+	for {
+		select {
+		case msg := <-l.messages:
+			if e := l.write(msg); e != nil {
+				err = e
+			}
+		default:
+			return err
+		}
+	}
+}
+
+// Flush writes pending messages to syslog
+func (l *syslog) Flush() error {
+	ack := make(chan error, 1)
+
+	select {
+	case l.syncs <- ack:
+	case <-l.done:
+		return nil
+	}
+
+	return <-ack
 }
 
 func (l *syslog) run(ready func()) {
@@ -119,7 +188,11 @@ func (l *syslog) run(ready func()) {
 		select {
 		case msg := <-l.messages:
 			l.write(msg)
+		case ack := <-l.syncs:
+			ack <- l.drain()
 		case <-l.closeNotify:
+			l.closeErr = l.drain()
+			close(l.done)
 			return
 		}
 	}
@@ -0,0 +1,256 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// templateSegment writes a part of message to buffer
+type templateSegment func(buf *bytes.Buffer, msg *Message)
+
+// TemplateFormatter formats message by a layout compiled once by NewTemplateFormatter
+type TemplateFormatter struct {
+	segments []templateSegment
+	bp       bufferPool
+}
+
+// NewTemplateFormatter compiles layout to a formatter. Layout is text with tokens in
+// the form of {name} or {name:options}, options are separated by '|', "{{" and "}}"
+// are written as '{' and '}'. Supported tokens are:
+//
+//	{time:layout|zone}   timestamp in Go time layout or TimeUnix, TimeUnixMilli and
+//	                     TimeElapsed, zone is UTC, Local or a location name like
+//	                     Asia/Shanghai, default local time in 2006/01/02 15:04:05.000
+//	{level:upper|lower}  level name, e.g. Info, INFO or info
+//	{tag}                level tag, e.g. [I]
+//	{caller}             filename and line of caller, e.g. log.go:42
+//	{file} {line} {func} filename, line and function of caller
+//	{msg}                message
+//	{field:key}          value of the field
+//	{fields:a|b|-c}      fields in the form of key=value, only a and b are included if
+//	                     given, and c is excluded
+//	{stack}              stack trace
+//
+// An integer option pads the value to width like fmt does, e.g. {level:5} is right
+// aligned and {level:-5|upper} is left aligned.
+//
+//	{time:2006-01-02T15:04:05Z07:00|UTC} {level:-5|upper} {caller} {msg} {fields}
+func NewTemplateFormatter(layout string) (*TemplateFormatter, error) {
+	var (
+		tf      = &TemplateFormatter{}
+		literal strings.Builder
+	)
+
+	flush := func() {
+		if literal.Len() > 0 {
+			text := literal.String()
+			tf.segments = append(tf.segments, func(buf *bytes.Buffer, _ *Message) {
+				buf.WriteString(text)
+			})
+			literal.Reset()
+		}
+	}
+
+	for i := 0; i < len(layout); i++ {
+		switch ch := layout[i]; {
+		case ch == '{' && i+1 < len(layout) && layout[i+1] == '{':
+			literal.WriteByte('{')
+			i++
+		case ch == '}' && i+1 < len(layout) && layout[i+1] == '}':
+			literal.WriteByte('}')
+			i++
+		case ch == '}':
+			return nil, fmt.Errorf("unexpected '}' at %d of template layout", i)
+		case ch == '{':
+			end := strings.IndexByte(layout[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed '{' at %d of template layout", i)
+			}
+
+			segment, err := compileToken(layout[i+1 : i+end])
+			if err != nil {
+				return nil, err
+			}
+
+			flush()
+			tf.segments = append(tf.segments, segment)
+			i += end
+		default:
+			literal.WriteByte(ch)
+		}
+	}
+
+	flush()
+
+	return tf, nil
+}
+
+func (tf *TemplateFormatter) Format(msg *Message) (message string) {
+	buf := tf.bp.acquire()
+
+	for _, segment := range tf.segments {
+		segment(buf, msg)
+	}
+
+	message = buf.String()
+	tf.bp.recycle(buf)
+
+	return message
+}
+
+// compileToken compiles token in the form of name:options to segment
+func compileToken(token string) (templateSegment, error) {
+	var (
+		name, spec = token, ""
+		options    []string
+		width      int
+		value      func(msg *Message) string
+	)
+
+	if index := strings.IndexByte(token, ':'); index >= 0 {
+		name, spec = token[:index], token[index+1:]
+		options = strings.Split(spec, "|")
+	}
+
+	// integer options are width of value, except for time layout
+	rest := options[:0:0]
+	for i, option := range options {
+		if n, err := strconv.Atoi(option); err == nil && !(name == "time" && i == 0) {
+			width = n
+		} else {
+			rest = append(rest, option)
+		}
+	}
+	options = rest
+
+	switch name {
+	case "time":
+		format := TimeFormat{}
+		if len(options) > 0 {
+			format.Layout = options[0]
+		}
+
+		if len(options) > 1 {
+			var err error
+			if format.Location, err = time.LoadLocation(options[1]); err != nil {
+				return nil, fmt.Errorf("invalid time zone of template token '%s', %v", token, err)
+			}
+		}
+
+		value = func(msg *Message) string {
+			var num [64]byte
+			return string(format.appendTime(num[:0], msg.Timestamp, defaultTimelayout))
+		}
+	case "level":
+		value = func(msg *Message) string { return msg.Level.String() }
+		for _, option := range options {
+			switch option {
+			case "upper":
+				value = func(msg *Message) string { return strings.ToUpper(msg.Level.String()) }
+			case "lower":
+				value = func(msg *Message) string { return strings.ToLower(msg.Level.String()) }
+			default:
+				return nil, fmt.Errorf("invalid option '%s' of template token '%s'", option, token)
+			}
+		}
+	case "tag":
+		value = func(msg *Message) string { return msg.Level.Tag() }
+	case "caller":
+		value = func(msg *Message) string {
+			if msg.Filename == "" {
+				return ""
+			}
+			return msg.Filename + ":" + strconv.Itoa(msg.Line)
+		}
+	case "file":
+		value = func(msg *Message) string { return msg.Filename }
+	case "line":
+		value = func(msg *Message) string {
+			if msg.Filename == "" {
+				return ""
+			}
+			return strconv.Itoa(msg.Line)
+		}
+	case "func":
+		value = func(msg *Message) string { return msg.Function }
+	case "msg", "message":
+		value = func(msg *Message) string { return msg.Message }
+	case "stack":
+		value = func(msg *Message) string { return msg.Stack }
+	case "field":
+		if len(options) != 1 || options[0] == "" {
+			return nil, fmt.Errorf("template token '%s' requires a field key", token)
+		}
+
+		key := options[0]
+		value = func(msg *Message) string {
+			if v, ok := msg.Fields[key]; ok {
+				return logfmtString(v)
+			}
+			return ""
+		}
+	case "fields":
+		return compileFields(options), nil
+	default:
+		return nil, fmt.Errorf("unknown template token '%s'", token)
+	}
+
+	if width == 0 {
+		return func(buf *bytes.Buffer, msg *Message) {
+			buf.WriteString(value(msg))
+		}, nil
+	}
+
+	format := "%" + strconv.Itoa(width) + "s"
+	return func(buf *bytes.Buffer, msg *Message) {
+		fmt.Fprintf(buf, format, value(msg))
+	}, nil
+}
+
+// compileFields compiles fields token, options are keys included, or excluded if
+// prefixed by '-'
+func compileFields(options []string) templateSegment {
+	var (
+		included []string
+		excluded = make(map[string]struct{})
+	)
+
+	for _, option := range options {
+		if strings.HasPrefix(option, "-") {
+			excluded[option[1:]] = struct{}{}
+		} else if option != "" {
+			included = append(included, option)
+		}
+	}
+
+	return func(buf *bytes.Buffer, msg *Message) {
+		keys := included
+		if len(keys) == 0 {
+			keys = msg.FieldKeys()
+		}
+
+		sep := false
+		for _, key := range keys {
+			value, ok := msg.Fields[key]
+			if !ok {
+				continue
+			}
+
+			if _, ok = excluded[key]; ok {
+				continue
+			}
+
+			if sep {
+				buf.WriteByte(' ')
+			}
+			sep = true
+
+			writeLogfmtKey(buf, key)
+			buf.WriteByte('=')
+			writeLogfmtValue(buf, logfmtString(value))
+		}
+	}
+}